@@ -20,6 +20,7 @@ type Driver interface {
 
 type Txer interface {
 	Begin() (*sql.Tx, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
 
 type Execer interface {
@@ -97,7 +98,10 @@ type QuickSql interface {
 	StmtIn(paramStartIdx, paramLen int) string
 
 	// auto commit when the func is return nil, or auto rollback when the func is error
-	Commit(func(tx *sql.Tx) error) error
+	Commit(func(tx *Tx) error) error
+	// Same as Commit, but begin the tx with the context and options,
+	// the tx will be rollback when the context is done.
+	CommitContext(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error
 }
 
 func NewDB(drvName string, db *sql.DB) *DB {
@@ -119,100 +123,104 @@ func Close(closer io.Closer) {
 		return
 	}
 	if err := closer.Close(); err != nil {
-		println(errors.As(err).Error())
-		debug.PrintStack()
+		log.Error(context.TODO(), "close failed", "err", errors.As(err), "stack", string(debug.Stack()))
 	}
 }
 
 // A lazy function to rollback the *sql.Tx
 func Rollback(tx *sql.Tx) {
 	if err := tx.Rollback(); err != nil {
+		if err == sql.ErrTxDone {
+			// the tx has been rollback by the context
+			return
+		}
 		// roll back error is a serious error
-		log.Println(errors.As(err))
+		log.Error(context.TODO(), "rollback failed", "err", errors.As(err))
 	}
 }
 
 // A lazy function to commit the *sql.Tx
-func Commit(txer Txer, fn func(tx *sql.Tx) error) error {
-	tx, err := txer.Begin()
-	if err != nil {
-		return errors.As(err)
-	}
-	if err := fn(tx); err != nil {
-		Rollback(tx)
-		return err
-	}
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+// the driver name of qsql.Tx is set when the txer implements DriverName(), like qsql.DB.
+func Commit(txer Txer, fn func(tx *Tx) error) error {
+	return commitTx(txer, context.TODO(), nil, fn)
+}
+
+// Same as Commit, but begin the tx with the context and options.
+// Example for a read only transaction:
+// qsql.CommitContext(mdb, ctx, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, fn)
+func CommitContext(txer Txer, ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	return commitTx(txer, ctx, opts, fn)
 }
 
+// the operation is intercepted when the exec is qsql.DB or qsql.Tx.
 func InsertStruct(drvName string, exec Execer, obj interface{}, tbName string) (sql.Result, error) {
-	return insertStruct(exec, context.TODO(), obj, tbName, drvName)
+	return InsertStructContext(drvName, exec, context.TODO(), obj, tbName)
 }
 func InsertStructContext(drvName string, exec Execer, ctx context.Context, obj interface{}, tbName string) (sql.Result, error) {
-	return insertStruct(exec, ctx, obj, tbName, drvName)
+	return opInsertStruct(runnerOf(getDrvName(exec, drvName), exec), ctx, obj, tbName)
 }
 
 func ScanStructs(rows *sql.Rows, obj interface{}) error {
 	return scanStructs(rows, obj)
 }
 
+// the operation is intercepted when the queryer is qsql.DB or qsql.Tx, so as the others.
+// the ':name' params are bound by the driver of queryer, it's detected when the queryer is a sql.DB,
+// but unknow for a sql.Tx and bound to '?', using a qsql.Tx instead.
 func QueryStruct(queryer Queryer, obj interface{}, querySql string, args ...interface{}) error {
-	return queryStruct(queryer, context.TODO(), obj, querySql, args...)
+	return QueryStructContext(queryer, context.TODO(), obj, querySql, args...)
 }
 func QueryStructContext(queryer Queryer, ctx context.Context, obj interface{}, querySql string, args ...interface{}) error {
-	return queryStruct(queryer, ctx, obj, querySql, args...)
+	return opQueryStruct(runnerOf("", queryer), ctx, obj, querySql, args)
 }
 
 func QueryStructs(queryer Queryer, obj interface{}, querySql string, args ...interface{}) error {
-	return queryStructs(queryer, context.TODO(), obj, querySql, args...)
+	return QueryStructsContext(queryer, context.TODO(), obj, querySql, args...)
 }
 func QueryStructsContext(queryer Queryer, ctx context.Context, obj interface{}, querySql string, args ...interface{}) error {
-	return queryStructs(queryer, ctx, obj, querySql, args...)
+	return opQueryStructs(runnerOf("", queryer), ctx, obj, querySql, args)
 }
 
 func QueryElem(queryer Queryer, result interface{}, querySql string, args ...interface{}) error {
-	return queryElem(queryer, context.TODO(), result, querySql, args...)
+	return QueryElemContext(queryer, context.TODO(), result, querySql, args...)
 }
 func QueryElemContext(queryer Queryer, ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
-	return queryElem(queryer, ctx, result, querySql, args...)
+	return opQueryElem(runnerOf("", queryer), ctx, result, querySql, args)
 }
 
 func QueryElems(queryer Queryer, result interface{}, querySql string, args ...interface{}) error {
-	return queryElems(queryer, context.TODO(), result, querySql, args...)
+	return QueryElemsContext(queryer, context.TODO(), result, querySql, args...)
 }
 func QueryElemsContext(queryer Queryer, ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
-	return queryElems(queryer, ctx, result, querySql, args...)
+	return opQueryElems(runnerOf("", queryer), ctx, result, querySql, args)
 }
 
 func QueryPageArr(queryer Queryer, querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
-	return queryPageArr(queryer, context.TODO(), querySql, args...)
+	return QueryPageArrContext(queryer, context.TODO(), querySql, args...)
 }
 func QueryPageArrContext(queryer Queryer, ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
-	return queryPageArr(queryer, ctx, querySql, args...)
+	return opQueryPageArr(runnerOf("", queryer), ctx, querySql, args)
 }
 
 func QueryDBDataArr(queryer Queryer, querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
-	return queryDBDataArr(queryer, context.TODO(), querySql, args...)
+	return QueryDBDataArrContext(queryer, context.TODO(), querySql, args...)
 }
 func QueryDBDataArrContext(queryer Queryer, ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
-	return queryDBDataArr(queryer, ctx, querySql, args...)
+	return opQueryDBDataArr(runnerOf("", queryer), ctx, querySql, args)
 }
 
 func QueryPageMap(queryer Queryer, querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
-	return queryPageMap(queryer, context.TODO(), querySql, args...)
+	return QueryPageMapContext(queryer, context.TODO(), querySql, args...)
 }
 func QueryPageMapContext(queryer Queryer, ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
-	return queryPageMap(queryer, ctx, querySql, args...)
+	return opQueryPageMap(runnerOf("", queryer), ctx, querySql, args)
 }
 
 func QueryDBDataMap(queryer Queryer, querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
-	return queryDBDataMap(queryer, context.TODO(), querySql, args...)
+	return QueryDBDataMapContext(queryer, context.TODO(), querySql, args...)
 }
 func QueryDBDataMapContext(queryer Queryer, ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
-	return queryDBDataMap(queryer, ctx, querySql, args...)
+	return opQueryDBDataMap(runnerOf("", queryer), ctx, querySql, args)
 }
 
 func StmtIn(paramStartIdx, paramsLen int, drvName ...string) string {
@@ -0,0 +1,72 @@
+package qsql
+
+import (
+	"database/sql"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/gwaylib/errors"
+)
+
+// The error of the query and exec functions, it wraps the error of driver.
+//
+// The errors.Is(err, sql.ErrNoRows) and errors.ErrNoData.Equal(err) are true when data not found,
+// and the Error() keeps the format of github.com/gwaylib/errors with the code of cause.
+type QueryError struct {
+	Op       string
+	Sql      string
+	Args     []interface{} // redacted by the policy of db
+	Driver   string
+	Duration time.Duration
+	Err      error
+
+	caller string
+}
+
+func (e *QueryError) Error() string {
+	cause := errors.ParseError(e.Err)
+	data := append([]interface{}{cause.Code()}, cause.Stack()...)
+	data = append(data, []interface{}{e.caller, e.Op, e.Driver, e.Sql, e.Args, e.Duration.String()})
+	out, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf("%+v", data)
+	}
+	return string(out)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Implement the errors.Is for sql.ErrNoRows and the errors of github.com/gwaylib/errors.
+func (e *QueryError) Is(target error) bool {
+	if target == sql.ErrNoRows {
+		return errors.ErrNoData.Equal(e.Err)
+	}
+	if t, ok := target.(errors.Error); ok {
+		return t.Equal(e.Err)
+	}
+	return false
+}
+
+// Wrap the error of operation to a QueryError, the operation without sql is not wrapped, like Commit.
+func wrapQueryError(op *Operation, err error) error {
+	if err == nil || len(op.Sql) == 0 {
+		return err
+	}
+	var qErr *QueryError
+	if stderrors.As(err, &qErr) {
+		return err
+	}
+	return &QueryError{
+		Op:       op.Name,
+		Sql:      op.Sql,
+		Args:     op.RedactedArgs(),
+		Driver:   op.Driver,
+		Duration: op.Duration,
+		Err:      err,
+		caller:   callerOutside(),
+	}
+}
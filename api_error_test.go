@@ -0,0 +1,85 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/gwaylib/errors"
+)
+
+func TestQueryError(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	user := txTestUser{}
+	err := mdb.QueryStruct(&user, "SELECT * FROM user WHERE username=?", "none")
+	if !stderrors.Is(err, sql.ErrNoRows) || !stderrors.Is(err, errors.ErrNoData) || !errors.ErrNoData.Equal(err) {
+		t.Fatalf("expect no data, but: %v", err)
+	}
+	var qErr *QueryError
+	if !stderrors.As(err, &qErr) {
+		t.Fatalf("expect QueryError, but: %T", err)
+	}
+	if qErr.Op != "QueryStruct" || qErr.Driver != "sqlite" || qErr.Sql != "SELECT * FROM user WHERE username=?" ||
+		len(qErr.Args) != 1 || qErr.Args[0] != "none" || qErr.Duration <= 0 {
+		t.Fatalf("unexpect query error: %+v", qErr)
+	}
+	if !strings.Contains(err.Error(), "SELECT * FROM user WHERE username=?") || !strings.Contains(err.Error(), "api_error_test.go") {
+		t.Fatalf("expect sql and caller in error: %s", err.Error())
+	}
+
+	// the error of driver
+	count := 0
+	err = mdb.QueryElem(&count, "SELECT COUNT(*) FROM unknow")
+	if !stderrors.As(err, &qErr) || stderrors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("unexpect error: %v", err)
+	}
+	if _, ok := qErr.Err.(interface{ Code() int }); !ok {
+		t.Fatalf("expect the driver error, but: %T", qErr.Err)
+	}
+
+	// the others are returned as QueryError too.
+	for name, err := range map[string]error{
+		"raw": QueryElem(mdb.DB, &count, "SELECT COUNT(*) FROM unknow"),
+		"page": func() error {
+			_, err := NewPageSql("SELECT COUNT(*) FROM unknow", "").QueryCount(mdb)
+			return err
+		}(),
+		"exec": func() error {
+			_, err := mdb.Exec("DELETE FROM unknow")
+			return err
+		}(),
+		"tx": mdb.Commit(func(tx *Tx) error {
+			return tx.QueryElem(&count, "SELECT COUNT(*) FROM unknow")
+		}),
+		"savepoint": mdb.Commit(func(tx *Tx) error {
+			return tx.Commit(func(tx *Tx) error {
+				return tx.QueryElem(&count, "SELECT COUNT(*) FROM unknow")
+			})
+		}),
+		"retry": func() error {
+			// canceled when waiting for the retry
+			ctx, cancel := context.WithCancel(context.TODO())
+			policy := &RetryPolicy{Retryable: func(string, error) bool { cancel(); return true }}
+			err := mdb.CommitRetryContext(ctx, nil, policy, func(tx *Tx) error {
+				return tx.QueryElem(&count, "SELECT COUNT(*) FROM unknow")
+			})
+			if !stderrors.Is(err, context.Canceled) {
+				t.Fatalf("expect canceled, but: %v", err)
+			}
+			return err
+		}(),
+		"shard": func() error {
+			RegCache("api_error_shard", mdb)
+			users := []txTestUser{}
+			return NewShard(ShardModulo, "api_error_shard").QueryStructsAll(context.TODO(), &users, "SELECT * FROM unknow")
+		}(),
+	} {
+		if !stderrors.As(err, &qErr) {
+			t.Fatalf("%s: expect QueryError, but: %T", name, err)
+		}
+	}
+}
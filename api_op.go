@@ -0,0 +1,254 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/gwaylib/errors"
+)
+
+// Run the operations with the hooks, implemented by qsql.DB and qsql.Tx.
+type runner interface {
+	DriverName() string
+	run(ctx context.Context, op *Operation, fn func(ctx context.Context, conn sqlConn, op *Operation) error) error
+}
+
+// Run the operations on the Queryer or Execer which is not a runner, like sql.DB,
+// it's traced by the default tracer and redacted by the global policy, but no hooks of db.
+type rawRunner struct {
+	drvName string
+	c       *rawConn
+}
+
+type rawConn struct {
+	Execer
+	Queryer
+}
+
+func (c *rawConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	p, ok := c.Queryer.(interface {
+		PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	})
+	if !ok {
+		return nil, errors.New("prepare not supported").As(query)
+	}
+	return p.PrepareContext(ctx, query)
+}
+
+// Return the runner of the Queryer or Execer, the driver name is detected from the sql.DB when it's empty.
+func runnerOf(drvName string, conn interface{}) runner {
+	if r, ok := conn.(runner); ok {
+		return r
+	}
+	if db, ok := conn.(*sql.DB); ok && len(drvName) == 0 {
+		drvName = sqlDBDrvName(db)
+	}
+	c := &rawConn{}
+	c.Execer, _ = conn.(Execer)
+	c.Queryer, _ = conn.(Queryer)
+	return &rawRunner{drvName: drvName, c: c}
+}
+
+func (r *rawRunner) DriverName() string {
+	return r.drvName
+}
+
+func (r *rawRunner) run(ctx context.Context, op *Operation, fn func(ctx context.Context, conn sqlConn, op *Operation) error) error {
+	return runOp(nil, nil, ctx, r.c, op, fn)
+}
+
+func newOp(r runner, name, querySql string, args []interface{}) *Operation {
+	return &Operation{
+		Name:   name,
+		Driver: r.DriverName(),
+		Sql:    querySql,
+		Args:   args,
+	}
+}
+
+// Return the length of slice pointer, 0 if it's not a slice.
+func sliceLen(slicePtr interface{}) int {
+	v := reflect.Indirect(reflect.ValueOf(slicePtr))
+	if v.Kind() != reflect.Slice {
+		return 0
+	}
+	return v.Len()
+}
+
+func opExec(r runner, ctx context.Context, query string, args []interface{}) (sql.Result, error) {
+	var result sql.Result
+	op := newOp(r, "Exec", query, args)
+	if err := r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		res, err := conn.ExecContext(ctx, op.Sql, op.Args...)
+		if err != nil {
+			return err
+		}
+		result = res
+		op.RowsAffected, _ = res.RowsAffected()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func opQuery(r runner, ctx context.Context, query string, args []interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	op := newOp(r, "Query", query, args)
+	op.rowsOut = true
+	if err := r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		res, err := conn.QueryContext(ctx, op.Sql, op.Args...)
+		if err != nil {
+			return err
+		}
+		rows, err = takeRows(op, res)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// The row is read before the operation done, so the scan error and no data are passed to the interceptors,
+// and the row returns the error of operation when it's stopped by the interceptor.
+func opQueryRow(r runner, ctx context.Context, query string, args []interface{}) *sql.Row {
+	var row *bufferedRow
+	op := newOp(r, "QueryRow", query, args)
+	err := r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		rows, err := conn.QueryContext(ctx, op.Sql, op.Args...)
+		if err != nil {
+			return err
+		}
+		row, err = readBufferedRow(rows)
+		if err != nil {
+			return err
+		}
+		op.RowsAffected = 1
+		return nil
+	})
+	if errors.ErrNoData.Equal(err) && row != nil {
+		// the row returns sql.ErrNoRows when scan
+		return makeRow(row, nil)
+	}
+	if err != nil {
+		return makeRow(nil, err)
+	}
+	return makeRow(row, nil)
+}
+
+func opInsertStruct(r runner, ctx context.Context, obj interface{}, tbName string) (sql.Result, error) {
+	execSql, fields, err := reflectInsertSql(obj, tbName, r.DriverName())
+	if err != nil {
+		return nil, err
+	}
+	var result sql.Result
+	op := newOp(r, "InsertStruct", execSql, fields.Values)
+	op.redactPos = fields.Redacts
+	if err := r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		res, err := execInsertStruct(conn, ctx, op.Sql, op.Args, fields)
+		if err != nil {
+			return err
+		}
+		result = res
+		op.RowsAffected, _ = res.RowsAffected()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func opQueryStruct(r runner, ctx context.Context, obj interface{}, querySql string, args []interface{}) error {
+	op := newOp(r, "QueryStruct", querySql, args)
+	return r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		if err := queryStruct(conn, ctx, obj, op.Sql, op.Args...); err != nil {
+			return err
+		}
+		op.RowsAffected = 1
+		return nil
+	})
+}
+
+func opQueryStructs(r runner, ctx context.Context, obj interface{}, querySql string, args []interface{}) error {
+	op := newOp(r, "QueryStructs", querySql, args)
+	return r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		oldLen := sliceLen(obj)
+		if err := queryStructs(conn, ctx, obj, op.Sql, op.Args...); err != nil {
+			return err
+		}
+		op.RowsAffected = int64(sliceLen(obj) - oldLen)
+		return nil
+	})
+}
+
+func opQueryElem(r runner, ctx context.Context, result interface{}, querySql string, args []interface{}) error {
+	op := newOp(r, "QueryElem", querySql, args)
+	return r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		if err := queryElem(conn, ctx, result, op.Sql, op.Args...); err != nil {
+			return err
+		}
+		op.RowsAffected = 1
+		return nil
+	})
+}
+
+func opQueryElems(r runner, ctx context.Context, result interface{}, querySql string, args []interface{}) error {
+	op := newOp(r, "QueryElems", querySql, args)
+	return r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		oldLen := sliceLen(result)
+		if err := queryElems(conn, ctx, result, op.Sql, op.Args...); err != nil {
+			return err
+		}
+		op.RowsAffected = int64(sliceLen(result) - oldLen)
+		return nil
+	})
+}
+
+func opQueryPageArr(r runner, ctx context.Context, querySql string, args []interface{}) (titles []string, result [][]interface{}, err error) {
+	titles, result = []string{}, [][]interface{}{}
+	op := newOp(r, "QueryPageArr", querySql, args)
+	err = r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		var qErr error
+		titles, result, qErr = queryPageArr(conn, ctx, op.Sql, op.Args...)
+		op.RowsAffected = int64(len(result))
+		return qErr
+	})
+	return titles, result, err
+}
+
+func opQueryDBDataArr(r runner, ctx context.Context, querySql string, args []interface{}) (titles []string, result [][]*DBData, err error) {
+	titles, result = []string{}, [][]*DBData{}
+	op := newOp(r, "QueryDBDataArr", querySql, args)
+	err = r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		var qErr error
+		titles, result, qErr = queryDBDataArr(conn, ctx, op.Sql, op.Args...)
+		op.RowsAffected = int64(len(result))
+		return qErr
+	})
+	return titles, result, err
+}
+
+func opQueryPageMap(r runner, ctx context.Context, querySql string, args []interface{}) (titles []string, result []map[string]interface{}, err error) {
+	titles, result = []string{}, []map[string]interface{}{}
+	op := newOp(r, "QueryPageMap", querySql, args)
+	err = r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		var qErr error
+		titles, result, qErr = queryPageMap(conn, ctx, op.Sql, op.Args...)
+		op.RowsAffected = int64(len(result))
+		return qErr
+	})
+	return titles, result, err
+}
+
+func opQueryDBDataMap(r runner, ctx context.Context, querySql string, args []interface{}) (titles []string, result []map[string]*DBData, err error) {
+	titles, result = []string{}, []map[string]*DBData{}
+	op := newOp(r, "QueryDBDataMap", querySql, args)
+	err = r.run(ctx, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		var qErr error
+		titles, result, qErr = queryDBDataMap(conn, ctx, op.Sql, op.Args...)
+		op.RowsAffected = int64(len(result))
+		return qErr
+	})
+	return titles, result, err
+}
@@ -3,8 +3,6 @@ package qsql
 import (
 	"context"
 	"fmt"
-
-	"github.com/gwaylib/errors"
 )
 
 type PageSql struct {
@@ -28,40 +26,43 @@ func (p *PageSql) FmtPage(args ...interface{}) *PageSql {
 }
 
 func (p *PageSql) QueryCount(db *DB, args ...interface{}) (int64, error) {
+	return p.QueryCountContext(context.TODO(), db, args...)
+}
+func (p *PageSql) QueryCountContext(ctx context.Context, db *DB, args ...interface{}) (int64, error) {
 	count := int64(0)
-	if err := queryElem(db, context.TODO(), &count, p.countSql, args...); err != nil {
-		return 0, errors.As(err)
+	if err := db.QueryElemContext(ctx, &count, p.countSql, args...); err != nil {
+		return 0, err
 	}
 	return count, nil
 }
 
 func (p *PageSql) QueryPageArr(db *DB, args ...interface{}) ([]string, [][]interface{}, error) {
-	titles, data, err := queryPageArr(db, context.TODO(), p.querySql, args...)
+	titles, data, err := db.QueryPageArr(p.querySql, args...)
 	if err != nil {
-		return nil, nil, errors.As(err)
+		return nil, nil, err
 	}
 	return titles, data, nil
 }
 func (p *PageSql) QueryDBDataArr(db *DB, args ...interface{}) ([]string, [][]*DBData, error) {
-	titles, data, err := queryDBDataArr(db, context.TODO(), p.querySql, args...)
+	titles, data, err := db.QueryDBDataArr(p.querySql, args...)
 	if err != nil {
-		return nil, nil, errors.As(err)
+		return nil, nil, err
 	}
 	return titles, data, nil
 }
 
 func (p *PageSql) QueryPageMap(db *DB, args ...interface{}) ([]string, []map[string]interface{}, error) {
-	titles, data, err := queryPageMap(db, context.TODO(), p.querySql, args...)
+	titles, data, err := db.QueryPageMap(p.querySql, args...)
 	if err != nil {
-		return nil, nil, errors.As(err)
+		return nil, nil, err
 	}
 	return titles, data, nil
 }
 
 func (p *PageSql) QueryDBDataMap(db *DB, args ...interface{}) ([]string, []map[string]*DBData, error) {
-	titles, data, err := queryDBDataMap(db, context.TODO(), p.querySql, args...)
+	titles, data, err := db.QueryDBDataMap(p.querySql, args...)
 	if err != nil {
-		return nil, nil, errors.As(err)
+		return nil, nil, err
 	}
 	return titles, data, nil
 }
@@ -4,8 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"sync"
-
-	"github.com/gwaylib/errors"
+	"sync/atomic"
+	"time"
 )
 
 // qsql.DB Extendd sql.DB
@@ -15,6 +15,40 @@ type DB struct {
 	drvName string
 	isClose bool
 	mu      sync.Mutex
+
+	opts     atomic.Value // *dbOptions
+	health   dbHealth
+	inflight dbInflight
+	rebinds  dbRebind
+	stmts    dbStmtCache
+}
+
+// The options of db, it's copied when set for reading without lock.
+type dbOptions struct {
+	name         string
+	panicAsError bool
+	interceptors []Interceptor
+	slowQuery    time.Duration
+	tracer       Tracer
+	redact       *RedactPolicy
+	queryTimeout time.Duration
+	rebind       bool
+}
+
+func (db *DB) options() *dbOptions {
+	opts, ok := db.opts.Load().(*dbOptions)
+	if !ok {
+		return &dbOptions{}
+	}
+	return opts
+}
+
+func (db *DB) setOptions(fn func(o *dbOptions)) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	opts := *db.options()
+	fn(&opts)
+	db.opts.Store(&opts)
 }
 
 func _checkQuickSql() QuickSql {
@@ -32,27 +66,90 @@ func (db *DB) DriverName() string {
 	return db.drvName
 }
 
+// Return the name of db, it's the key of cache when registered to the cache.
+func (db *DB) Name() string {
+	if db == nil {
+		return ""
+	}
+	return db.options().name
+}
+
+func (db *DB) setName(name string) {
+	db.setOptions(func(o *dbOptions) {
+		o.name = name
+	})
+}
+
 func (db *DB) IsClose() bool {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 	return db.isClose
 }
 
+// Set how to handle a panic in the function of Commit,
+// the tx is always rollback when panic, and then panic again by default,
+// or return the panic as an ErrCommitPanic error with the stack when asError is true.
+func (db *DB) SetCommitPanicAsError(asError bool) {
+	db.setOptions(func(o *dbOptions) {
+		o.panicAsError = asError
+	})
+}
+
 func (db *DB) Close() error {
+	db.StopHealthCheck()
+	db.stmts.closeAll()
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
 	db.isClose = true
-	rmCache(db)
+	// the replicas of cluster are closed with the primary, they are not in cache any more.
+	for _, replica := range rmCache(db) {
+		replica.Close()
+	}
 	return db.DB.Close()
 }
 
+// Same as sql.DB.Exec, but intercepted by the interceptors of db.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return opExec(db, context.TODO(), query, args)
+}
+
+// Same as sql.DB.ExecContext, but run on the tx when the ctx carried a tx of the db.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return opExec(db, ctx, query, args)
+}
+
+// Same as sql.DB.Query, but intercepted by the interceptors of db.
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return opQuery(db, context.TODO(), query, args)
+}
+
+// Same as sql.DB.QueryContext, but run on the tx when the ctx carried a tx of the db.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return opQuery(db, ctx, query, args)
+}
+
+// Same as sql.DB.QueryRow, but intercepted by the interceptors of db.
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return opQueryRow(db, context.TODO(), query, args)
+}
+
+// Same as sql.DB.QueryRowContext, but run on the tx when the ctx carried a tx of the db.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return opQueryRow(db, ctx, query, args)
+}
+
+// Same as sql.DB.PrepareContext, but prepare on the tx when the ctx carried a tx of the db.
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.conn(ctx).PrepareContext(ctx, query)
+}
+
 // Reflect one db data to the struct. the struct tag format like `db:"field_title"`, reference to: http://github.com/jmoiron/sqlx
 func (db *DB) InsertStruct(structPtr interface{}, tbName string) (sql.Result, error) {
-	return insertStruct(db, context.TODO(), structPtr, tbName, db.drvName)
+	return opInsertStruct(db, context.TODO(), structPtr, tbName)
 }
 func (db *DB) InsertStructContext(ctx context.Context, structPtr interface{}, tbName string) (sql.Result, error) {
-	return insertStruct(db, ctx, structPtr, tbName, db.drvName)
+	return opInsertStruct(db, ctx, structPtr, tbName)
 }
 
 // Reflect the sql.Rows to []struct array.
@@ -65,63 +162,63 @@ func (db *DB) ScanStructs(rows *sql.Rows, structsPtr interface{}) error {
 
 // Reflect the sql.Query result to a struct.
 func (db *DB) QueryStruct(structPtr interface{}, querySql string, args ...interface{}) error {
-	return queryStruct(db, context.TODO(), structPtr, querySql, args...)
+	return opQueryStruct(db, context.TODO(), structPtr, querySql, args)
 }
 func (db *DB) QueryStructContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
-	return queryStruct(db, ctx, structPtr, querySql, args...)
+	return opQueryStruct(db, ctx, structPtr, querySql, args)
 }
 
 // Reflect the sql.Query result to a struct array.
 // Return empty array if data not found.
 func (db *DB) QueryStructs(structPtr interface{}, querySql string, args ...interface{}) error {
-	return queryStructs(db, context.TODO(), structPtr, querySql, args...)
+	return opQueryStructs(db, context.TODO(), structPtr, querySql, args)
 }
 func (db *DB) QueryStructsContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
-	return queryStructs(db, ctx, structPtr, querySql, args...)
+	return opQueryStructs(db, ctx, structPtr, querySql, args)
 }
 
 // Query one field to a sql.Scanner.
 func (db *DB) QueryElem(result interface{}, querySql string, args ...interface{}) error {
-	return queryElem(db, context.TODO(), result, querySql, args...)
+	return opQueryElem(db, context.TODO(), result, querySql, args)
 }
 func (db *DB) QueryElemContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
-	return queryElem(db, ctx, result, querySql, args...)
+	return opQueryElem(db, ctx, result, querySql, args)
 }
 
 // Query one field to a sql.Scanner array.
 func (db *DB) QueryElems(result interface{}, querySql string, args ...interface{}) error {
-	return queryElems(db, context.TODO(), result, querySql, args...)
+	return opQueryElems(db, context.TODO(), result, querySql, args)
 }
 func (db *DB) QueryElemsContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
-	return queryElems(db, ctx, result, querySql, args...)
+	return opQueryElems(db, ctx, result, querySql, args)
 }
 
 // Reflect the query result to a string array.
 func (db *DB) QueryPageArr(querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
-	return queryPageArr(db, context.TODO(), querySql, args...)
+	return opQueryPageArr(db, context.TODO(), querySql, args)
 }
 func (db *DB) QueryPageArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
-	return queryPageArr(db, ctx, querySql, args...)
+	return opQueryPageArr(db, ctx, querySql, args)
 }
 func (db *DB) QueryDBDataArr(querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
-	return queryDBDataArr(db, context.TODO(), querySql, args...)
+	return opQueryDBDataArr(db, context.TODO(), querySql, args)
 }
 func (db *DB) QueryDBDataArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
-	return queryDBDataArr(db, ctx, querySql, args...)
+	return opQueryDBDataArr(db, ctx, querySql, args)
 }
 
 // Reflect the query result to a string map.
 func (db *DB) QueryPageMap(querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
-	return queryPageMap(db, context.TODO(), querySql, args...)
+	return opQueryPageMap(db, context.TODO(), querySql, args)
 }
 func (db *DB) QueryPageMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
-	return queryPageMap(db, ctx, querySql, args...)
+	return opQueryPageMap(db, ctx, querySql, args)
 }
 func (db *DB) QueryDBDataMap(querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
-	return queryDBDataMap(db, context.TODO(), querySql, args...)
+	return opQueryDBDataMap(db, context.TODO(), querySql, args)
 }
 func (db *DB) QueryDBDataMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
-	return queryDBDataMap(db, ctx, querySql, args...)
+	return opQueryDBDataMap(db, ctx, querySql, args)
 }
 
 // Return "?,?,?,?..." for default, or "@p1,@p2,@p3..." for mssql, or ":1,:2,:3..." for pgsql.
@@ -132,17 +229,24 @@ func (db *DB) StmtIn(paramStartIdx, paramsLen int) string {
 
 // A lazy function to commit the *sql.Tx
 // if will auto commit when the function is nil error, or do a rollback and return the function error.
-func (db *DB) Commit(fn func(*sql.Tx) error) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return errors.As(err)
-	}
-	if err := fn(tx); err != nil {
-		Rollback(tx)
-		return err
-	}
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+func (db *DB) Commit(fn func(*Tx) error) error {
+	return commitTx(db, context.TODO(), nil, fn)
+}
+
+// Same as Commit, but begin the tx with the context and options,
+// the tx will be rollback when the context is done.
+// It runs in a savepoint when the ctx carried a tx of the db, see WithTx.
+func (db *DB) CommitContext(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	return commitTx(db, ctx, opts, fn)
+}
+
+// Same as Commit, but rerun the whole function in a new tx when the tx failed by a deadlock or serialization failure.
+// the policy is using the default when it's nil.
+func (db *DB) CommitRetry(policy *RetryPolicy, fn func(*Tx) error) error {
+	return commitRetry(db, context.TODO(), nil, policy, fn)
+}
+
+// Same as CommitRetry with the context and options of tx.
+func (db *DB) CommitRetryContext(ctx context.Context, opts *sql.TxOptions, policy *RetryPolicy, fn func(*Tx) error) error {
+	return commitRetry(db, ctx, opts, policy, fn)
 }
@@ -1,7 +1,11 @@
 package qsql
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,31 +13,43 @@ import (
 	"github.com/gwaylib/errors"
 )
 
+var (
+	ErrCacheClosing = errors.New("cache is closing")
+)
+
 var (
 	cacheLock    = sync.Mutex{}
 	cacheIniPath string
+	cacheClosing bool // stop getting from cache by CloseCacheContext until registered again
 	cache        = map[string]*DB{}
+	clusterCache = map[string]*Cluster{}
 )
 
 func setCacheIni(iniPath string) {
 	cacheLock.Lock()
 	defer cacheLock.Unlock()
 	cacheIniPath = iniPath
+	cacheClosing = false
 }
 
 func regCache(key string, db *DB) {
 	cacheLock.Lock()
 	defer cacheLock.Unlock()
+	cacheClosing = false
 	_, ok := cache[key]
 	if ok {
 		panic("key is already exist: " + key)
 	}
+	db.setName(key)
 	cache[key] = db
 }
 
 func getCache(key string) (*DB, error) {
 	cacheLock.Lock()
 	defer cacheLock.Unlock()
+	if cacheClosing {
+		return nil, ErrCacheClosing.As(key)
+	}
 	db, ok := cache[key]
 	if !ok {
 		if len(cacheIniPath) == 0 {
@@ -48,27 +64,128 @@ func getCache(key string) (*DB, error) {
 	return db, nil
 }
 
-func rmCache(src *DB) {
+func regCluster(key string, c *Cluster) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheClosing = false
+	_, ok := cache[key]
+	if ok {
+		panic("key is already exist: " + key)
+	}
+	c.primary.setName(key)
+	for i, replica := range c.replicas {
+		replica.setName(fmt.Sprintf("%s.replica_%d", key, i+1))
+	}
+	cache[key] = c.primary
+	clusterCache[key] = c
+}
+
+func getCluster(key string) (*Cluster, error) {
+	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	if cacheClosing {
+		return nil, ErrCacheClosing.As(key)
+	}
+	c, ok := clusterCache[key]
+	if ok {
+		return c, nil
+	}
+	db, ok := cache[key]
+	if !ok {
+		if len(cacheIniPath) == 0 {
+			return nil, errors.ErrNoData.As(key)
+		}
+		if _, err := regCacheWithIni(cacheIniPath, key); err != nil {
+			return nil, errors.As(err)
+		}
+		if c, ok := clusterCache[key]; ok {
+			return c, nil
+		}
+		db = cache[key]
+	}
+	// a cluster without replica
+	c = NewCluster(db)
+	clusterCache[key] = c
+	return c, nil
+}
+
+// Remove the db from cache, and return the replicas of its cluster.
+func rmCache(src *DB) []*DB {
 	cacheLock.Lock()
 	defer cacheLock.Unlock()
 	for key, db := range cache {
 		if src == db {
+			var replicas []*DB
+			if c, ok := clusterCache[key]; ok {
+				replicas = c.replicas
+			}
 			delete(cache, key)
-			return
+			delete(clusterCache, key)
+			return replicas
 		}
 	}
+	return nil
 }
 
-func closeCache() {
+// Remove all the dbs from cache and return them.
+func popCache(closing bool) []*DB {
 	cacheLock.Lock()
+	defer cacheLock.Unlock()
+	cacheClosing = closing
 	dbs := make([]*DB, 0, len(cache))
 	for key, db := range cache {
 		dbs = append(dbs, db)
 		delete(cache, key)
 	}
-	cacheLock.Unlock()
+	for key, c := range clusterCache {
+		dbs = append(dbs, c.replicas...)
+		delete(clusterCache, key)
+	}
+	return dbs
+}
+
+func closeCache() {
+	closeDBs(popCache(false))
+}
+
+// Stop getting from cache, and wait the running operations done until the ctx done, then close the dbs.
+// Return the operations are still running when closing, and the error of ctx when it's done before all operations done.
+func closeCacheContext(ctx context.Context) ([]RunningOp, error) {
+	dbs := popCache(true)
+
+	var err error
+wait:
+	for _, db := range dbs {
+		idle := db.inflight.idleChan()
+		if idle == nil {
+			continue
+		}
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = errors.As(ctx.Err())
+			break wait
+		}
+	}
+
+	running := []RunningOp{}
+	for _, db := range dbs {
+		for _, op := range db.runningOps() {
+			log.Warn(ctx, "force closing the running operation",
+				"section", op.Section, "op", op.Name, "sql", op.Sql, "args", op.Args, "duration", time.Since(op.Start),
+			)
+			running = append(running, op)
+		}
+		db.inflight.cancelAll()
+	}
+	closeDBs(dbs)
+	return running, err
+}
 
+func closeDBs(dbs []*DB) {
 	for _, db := range dbs {
+		db.StopHealthCheck()
+		db.stmts.closeAll()
 		db.mu.Lock()
 		db.isClose = true
 		db.mu.Unlock()
@@ -76,6 +193,15 @@ func closeCache() {
 	}
 }
 
+// Parse the seconds like "30", or the duration like "500ms".
+func parseIniSeconds(val string) (time.Duration, error) {
+	sec, err := strconv.ParseInt(val, 10, 64)
+	if err == nil {
+		return time.Duration(sec) * time.Second, nil
+	}
+	return time.ParseDuration(val)
+}
+
 // ini content example
 //
 // [main]
@@ -85,6 +211,14 @@ func closeCache() {
 // max_idle_time:0 # seconds
 // max_idle_conns:0 # num
 // max_open_conns:0 # num
+// slow_query_ms:0 # milliseconds, log the query takes longer than it, 0 is disabled.
+// query_timeout:0 # seconds or a duration like 500ms, the default timeout of operations, 0 is disabled.
+// health_check_interval:0 # seconds or a duration like 500ms, ping the db in background to check the health, 0 is disabled.
+// rebind:false # rebind the '?' placeholders of raw sql to the driver.
+// # the replicas of cluster, the keys are prefixed by 'replica_dsn', and using the same driver and pool settings of the primary.
+// replica_dsn_1: username:passwd@tcp(127.0.0.2:3306)/main?timeout=30s&strict=true&loc=Local&parseTime=true&allowOldPasswords=1
+// replica_dsn_2: username:passwd@tcp(127.0.0.3:3306)/main?timeout=30s&strict=true&loc=Local&parseTime=true&allowOldPasswords=1
+// replica_balance: round_robin # round_robin or least_conn
 //
 // [log]
 // driver: mysql
@@ -146,28 +280,121 @@ func regCacheWithIni(iniFile, iniSection string) (*DB, error) {
 		}
 	}
 
+	slowQuery := int64(0)
+	slowQueryKey, err := section.GetKey("slow_query_ms")
+	if err == nil {
+		slowQuery, err = slowQueryKey.Int64()
+		if err != nil {
+			return nil, errors.As(err, "error slow_query_ms value")
+		}
+	}
+
+	queryTimeout := time.Duration(0)
+	queryTimeoutKey, err := section.GetKey("query_timeout")
+	if err == nil {
+		queryTimeout, err = parseIniSeconds(queryTimeoutKey.String())
+		if err != nil {
+			return nil, errors.As(err, "error query_timeout value")
+		}
+	}
+
+	healthInterval := time.Duration(0)
+	healthIntervalKey, err := section.GetKey("health_check_interval")
+	if err == nil {
+		healthInterval, err = parseIniSeconds(healthIntervalKey.String())
+		if err != nil {
+			return nil, errors.As(err, "error health_check_interval value")
+		}
+	}
+
+	rebind := false
+	rebindKey, err := section.GetKey("rebind")
+	if err == nil {
+		rebind, err = rebindKey.Bool()
+		if err != nil {
+			return nil, errors.As(err, "error rebind value")
+		}
+	}
+
+	replicaDsns := []string{}
+	for _, key := range section.Keys() {
+		if strings.HasPrefix(key.Name(), "replica_dsn") {
+			replicaDsns = append(replicaDsns, key.String())
+		}
+	}
+	balance := BALANCE_ROUND_ROBIN
+	balanceKey, err := section.GetKey("replica_balance")
+	if err == nil {
+		balance = balanceKey.String()
+		switch balance {
+		case BALANCE_ROUND_ROBIN, BALANCE_LEAST_CONN:
+		default:
+			return nil, errors.New("error replica_balance value").As(balance)
+		}
+	}
+
 	_, ok := cache[iniSection]
 	if ok {
 		return nil, errors.New("key is already exist").As(iniSection)
 	}
 
-	db, err := Open(drvName.String(), os.ExpandEnv(dsn.String()))
+	open := func(dsn string) (*DB, error) {
+		db, err := Open(drvName.String(), os.ExpandEnv(dsn))
+		if err != nil {
+			return nil, errors.As(err)
+		}
+		if lifeTime > 0 {
+			db.SetConnMaxLifetime(time.Duration(lifeTime) * time.Second)
+		}
+		if idleTime > 0 {
+			db.SetConnMaxIdleTime(time.Duration(idleTime) * time.Second)
+		}
+		if idleConns > 0 {
+			db.SetMaxIdleConns(idleConns)
+		}
+		if openConns > 0 {
+			db.SetMaxOpenConns(openConns)
+		}
+		if slowQuery > 0 {
+			db.SetSlowQuery(time.Duration(slowQuery) * time.Millisecond)
+		}
+		if queryTimeout > 0 {
+			db.SetQueryTimeout(queryTimeout)
+		}
+		if rebind {
+			db.SetRebind(rebind)
+		}
+		return db, nil
+	}
+	db, err := open(dsn.String())
 	if err != nil {
 		return nil, errors.As(err)
 	}
-	if lifeTime > 0 {
-		db.SetConnMaxLifetime(time.Duration(lifeTime) * time.Second)
-	}
-	if idleTime > 0 {
-		db.SetConnMaxIdleTime(time.Duration(idleTime) * time.Second)
-	}
-	if idleConns > 0 {
-		db.SetMaxIdleConns(idleConns)
+	db.setName(iniSection)
+	replicas := make([]*DB, 0, len(replicaDsns))
+	for i, replicaDsn := range replicaDsns {
+		replica, err := open(replicaDsn)
+		if err != nil {
+			for _, r := range replicas {
+				Close(r.DB)
+			}
+			Close(db.DB)
+			return nil, errors.As(err)
+		}
+		replica.setName(fmt.Sprintf("%s.replica_%d", iniSection, i+1))
+		replicas = append(replicas, replica)
 	}
-	if openConns > 0 {
-		db.SetMaxOpenConns(openConns)
+
+	if healthInterval > 0 {
+		db.StartHealthCheck(healthInterval)
+		for _, replica := range replicas {
+			replica.StartHealthCheck(healthInterval)
+		}
 	}
 
 	cache[iniSection] = db
+	if len(replicas) > 0 {
+		clusterCache[iniSection] = NewCluster(db, replicas...).SetBalance(balance)
+	}
 	return db, nil
 }
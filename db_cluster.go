@@ -0,0 +1,220 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+const (
+	// choose the replica by turns.
+	BALANCE_ROUND_ROBIN = "round_robin"
+	// choose the replica with least connections in use.
+	BALANCE_LEAST_CONN = "least_conn"
+)
+
+type primaryCtxKey struct{}
+
+// Return a new context to force the read query of qsql.Cluster run on the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
+
+func isPrimaryCtx(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	force, _ := ctx.Value(primaryCtxKey{}).(bool)
+	return force
+}
+
+// qsql.Cluster is a read/write splitting of one primary and N replicas,
+// the write operations like Exec, InsertStruct, Commit run on the primary,
+// and the read operations like Query* run on a healthy replica,
+// it will fallback to the primary when there is no healthy replica.
+//
+// Implement qsql.QuickSql interface
+type Cluster struct {
+	primary  *DB
+	replicas []*DB
+	balance  string
+
+	rrIdx uint32
+}
+
+func _checkQuickSqlCluster() QuickSql {
+	return &Cluster{}
+}
+
+func NewCluster(primary *DB, replicas ...*DB) *Cluster {
+	return &Cluster{
+		primary:  primary,
+		replicas: replicas,
+		balance:  BALANCE_ROUND_ROBIN,
+	}
+}
+
+// Set the balance of choosing replica, BALANCE_ROUND_ROBIN is default.
+func (c *Cluster) SetBalance(balance string) *Cluster {
+	switch balance {
+	case BALANCE_ROUND_ROBIN, BALANCE_LEAST_CONN:
+	default:
+		panic("unknow balance: " + balance)
+	}
+	c.balance = balance
+	return c
+}
+
+func (c *Cluster) Primary() *DB {
+	return c.primary
+}
+
+func (c *Cluster) Replicas() []*DB {
+	return c.replicas
+}
+
+// Return a healthy replica for reading,
+// or the primary when the ctx is WithPrimary, or the ctx carried a tx of primary, or there is no healthy replica.
+func (c *Cluster) Replica(ctx context.Context) *DB {
+	if isPrimaryCtx(ctx) || c.primary.ctxTx(ctx) != nil {
+		return c.primary
+	}
+	healthy := make([]*DB, 0, len(c.replicas))
+	for _, db := range c.replicas {
+		if isHealthy(db) {
+			healthy = append(healthy, db)
+		}
+	}
+	if len(healthy) == 0 {
+		return c.primary
+	}
+
+	switch c.balance {
+	case BALANCE_LEAST_CONN:
+		least := healthy[0]
+		leastInUse := least.Stats().InUse
+		for _, db := range healthy[1:] {
+			if inUse := db.Stats().InUse; inUse < leastInUse {
+				least, leastInUse = db, inUse
+			}
+		}
+		return least
+	default:
+		idx := atomic.AddUint32(&c.rrIdx, 1)
+		return healthy[int(idx%uint32(len(healthy)))]
+	}
+}
+
+// Close the primary and replicas
+func (c *Cluster) Close() error {
+	var result error
+	for _, db := range c.replicas {
+		if err := db.Close(); err != nil {
+			result = err
+		}
+	}
+	if err := c.primary.Close(); err != nil {
+		result = err
+	}
+	return result
+}
+
+func (c *Cluster) DriverName() string {
+	return c.primary.DriverName()
+}
+
+// Run on the primary
+func (c *Cluster) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.primary.Exec(query, args...)
+}
+func (c *Cluster) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.primary.ExecContext(ctx, query, args...)
+}
+
+// Run on a replica, use WithPrimary to query the primary.
+func (c *Cluster) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.Replica(context.TODO()).Query(query, args...)
+}
+func (c *Cluster) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.Replica(ctx).QueryContext(ctx, query, args...)
+}
+func (c *Cluster) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.Replica(context.TODO()).QueryRow(query, args...)
+}
+func (c *Cluster) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.Replica(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// Run on the primary
+func (c *Cluster) InsertStruct(structPtr interface{}, tbName string) (sql.Result, error) {
+	return c.primary.InsertStruct(structPtr, tbName)
+}
+func (c *Cluster) InsertStructContext(ctx context.Context, structPtr interface{}, tbName string) (sql.Result, error) {
+	return c.primary.InsertStructContext(ctx, structPtr, tbName)
+}
+
+func (c *Cluster) ScanStructs(rows *sql.Rows, structsPtr interface{}) error {
+	return scanStructs(rows, structsPtr)
+}
+
+// Run on a replica, use WithPrimary to query the primary.
+func (c *Cluster) QueryStruct(structPtr interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(context.TODO()).QueryStruct(structPtr, querySql, args...)
+}
+func (c *Cluster) QueryStructContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(ctx).QueryStructContext(ctx, structPtr, querySql, args...)
+}
+func (c *Cluster) QueryStructs(structPtr interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(context.TODO()).QueryStructs(structPtr, querySql, args...)
+}
+func (c *Cluster) QueryStructsContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(ctx).QueryStructsContext(ctx, structPtr, querySql, args...)
+}
+func (c *Cluster) QueryElem(result interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(context.TODO()).QueryElem(result, querySql, args...)
+}
+func (c *Cluster) QueryElemContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(ctx).QueryElemContext(ctx, result, querySql, args...)
+}
+func (c *Cluster) QueryElems(result interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(context.TODO()).QueryElems(result, querySql, args...)
+}
+func (c *Cluster) QueryElemsContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
+	return c.Replica(ctx).QueryElemsContext(ctx, result, querySql, args...)
+}
+func (c *Cluster) QueryPageArr(querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
+	return c.Replica(context.TODO()).QueryPageArr(querySql, args...)
+}
+func (c *Cluster) QueryPageArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
+	return c.Replica(ctx).QueryPageArrContext(ctx, querySql, args...)
+}
+func (c *Cluster) QueryDBDataArr(querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
+	return c.Replica(context.TODO()).QueryDBDataArr(querySql, args...)
+}
+func (c *Cluster) QueryDBDataArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
+	return c.Replica(ctx).QueryDBDataArrContext(ctx, querySql, args...)
+}
+func (c *Cluster) QueryPageMap(querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
+	return c.Replica(context.TODO()).QueryPageMap(querySql, args...)
+}
+func (c *Cluster) QueryPageMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
+	return c.Replica(ctx).QueryPageMapContext(ctx, querySql, args...)
+}
+func (c *Cluster) QueryDBDataMap(querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
+	return c.Replica(context.TODO()).QueryDBDataMap(querySql, args...)
+}
+func (c *Cluster) QueryDBDataMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
+	return c.Replica(ctx).QueryDBDataMapContext(ctx, querySql, args...)
+}
+
+func (c *Cluster) StmtIn(paramStartIdx, paramsLen int) string {
+	return c.primary.StmtIn(paramStartIdx, paramsLen)
+}
+
+// Run on the primary
+func (c *Cluster) Commit(fn func(*Tx) error) error {
+	return c.primary.Commit(fn)
+}
+func (c *Cluster) CommitContext(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	return c.primary.CommitContext(ctx, opts, fn)
+}
@@ -0,0 +1,128 @@
+package qsql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestNode(t *testing.T, dsn, name string) {
+	db, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+	if _, err := db.Exec("CREATE TABLE node (name VARCHAR(32))"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO node (name) VALUES (?)", name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	dir := t.TempDir()
+	primaryDsn := filepath.Join(dir, "primary.db")
+	replicaDsn := filepath.Join(dir, "replica.db")
+	openTestNode(t, primaryDsn, "primary")
+	openTestNode(t, replicaDsn, "replica")
+
+	iniPath := filepath.Join(dir, "db.cfg")
+	if err := os.WriteFile(iniPath, []byte(fmt.Sprintf(`
+[main]
+driver: sqlite
+dsn: %s
+replica_dsn_1: %s
+replica_balance: least_conn
+`, primaryDsn, replicaDsn)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	RegCacheWithIni(iniPath)
+	defer func() {
+		CloseCache()
+		RegCacheWithIni("")
+	}()
+
+	c := GetCluster("main")
+	if GetCache("main") != c.Primary() {
+		t.Fatal("expect the primary is cached")
+	}
+	if len(c.Replicas()) != 1 {
+		t.Fatalf("expect 1 replica, but: %d", len(c.Replicas()))
+	}
+
+	name := ""
+	if err := c.QueryElem(&name, "SELECT name FROM node"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "replica" {
+		t.Fatalf("expect query the replica, but: %s", name)
+	}
+	if err := c.QueryElemContext(WithPrimary(context.TODO()), &name, "SELECT name FROM node"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "primary" {
+		t.Fatalf("expect query the primary, but: %s", name)
+	}
+	if _, err := c.Exec("UPDATE node SET name=?", "primary1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Primary().QueryElem(&name, "SELECT name FROM node"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "primary1" {
+		t.Fatalf("expect exec on the primary, but: %s", name)
+	}
+
+	// fallback to the primary
+	Close(c.Replicas()[0])
+	if err := c.QueryElem(&name, "SELECT name FROM node"); err != nil {
+		t.Fatal(err)
+	}
+	if name != "primary1" {
+		t.Fatalf("expect query the primary, but: %s", name)
+	}
+}
+
+func TestClusterClosePrimary(t *testing.T) {
+	dir := t.TempDir()
+	primaryDsn := filepath.Join(dir, "primary.db")
+	replicaDsn := filepath.Join(dir, "replica.db")
+	openTestNode(t, primaryDsn, "primary")
+	openTestNode(t, replicaDsn, "replica")
+
+	iniPath := filepath.Join(dir, "db.cfg")
+	if err := os.WriteFile(iniPath, []byte(fmt.Sprintf(`
+[main]
+driver: sqlite
+dsn: %s
+replica_dsn_1: %s
+`, primaryDsn, replicaDsn)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	RegCacheWithIni(iniPath)
+	defer func() {
+		CloseCache()
+		RegCacheWithIni("")
+	}()
+
+	c := GetCluster("main")
+	replica := c.Replicas()[0]
+	if err := GetCache("main").Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !replica.IsClose() {
+		t.Fatal("expect the replica closed with the primary")
+	}
+
+	// reopen a new cluster
+	c1 := GetCluster("main")
+	if c1 == c || c1.Replicas()[0] == replica {
+		t.Fatal("expect a new cluster")
+	}
+	if c1.Replicas()[0].IsClose() {
+		t.Fatal("expect the new replica opened")
+	}
+}
@@ -1,5 +1,7 @@
 package qsql
 
+import "context"
+
 // Register a db to the connection pool by manully.
 func RegCache(key string, db *DB) {
 	regCache(key, db)
@@ -23,7 +25,35 @@ func HasCache(key string) (*DB, error) {
 	return getCache(key)
 }
 
+// Register a cluster to the connection pool by manully, the primary can be got by GetCache.
+func RegCluster(key string, c *Cluster) {
+	regCluster(key, c)
+}
+
+// Get the cluster instance from the cache,
+// the ini section with 'replica_dsn' keys is a cluster, others are the cluster only with the primary.
+func GetCluster(key string) *Cluster {
+	c, err := getCluster(key)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Checking the cache does it have a cluster instance.
+func HasCluster(key string) (*Cluster, error) {
+	return getCluster(key)
+}
+
 // Close all instance in the cache.
 func CloseCache() {
 	closeCache()
 }
+
+// Close all instance in the cache gracefully,
+// the GetCache and GetCluster are stopped with ErrCacheClosing until registered again,
+// and close the dbs after the running operations and transactions of Commit done, or force to close when the ctx done.
+// Return the operations are still running when force closing, and the error of ctx.
+func CloseCacheContext(ctx context.Context) ([]RunningOp, error) {
+	return closeCacheContext(ctx)
+}
@@ -0,0 +1,102 @@
+package qsql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The health state of db, it's updated by the health checker.
+type dbHealth struct {
+	mu      sync.Mutex
+	down    bool
+	lastErr error
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start a background health checker to ping the db at the interval, the ping timeout is the interval.
+// The unhealthy replicas are skipped by the qsql.Cluster, and the state transitions are logged.
+// It restarts the checker when it's running, and stops it when the interval is 0.
+func (db *DB) StartHealthCheck(interval time.Duration) {
+	if interval <= 0 {
+		db.StopHealthCheck()
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	db.swapHealthCheck(stop, done)
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			db.checkHealth(context.TODO(), interval)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop the background health checker and wait it exit, the state is kept.
+func (db *DB) StopHealthCheck() {
+	db.swapHealthCheck(nil, nil)
+}
+
+// Replace the running checker and wait the old one exit.
+func (db *DB) swapHealthCheck(stop, done chan struct{}) {
+	db.health.mu.Lock()
+	oldStop, oldDone := db.health.stop, db.health.done
+	db.health.stop, db.health.done = stop, done
+	db.health.mu.Unlock()
+	if oldStop != nil {
+		close(oldStop)
+		<-oldDone
+	}
+}
+
+// Return false when the db is closed or the last ping of health checker failed.
+func (db *DB) Healthy() bool {
+	if db.IsClose() {
+		return false
+	}
+	db.health.mu.Lock()
+	defer db.health.mu.Unlock()
+	return !db.health.down
+}
+
+// Return the error of the last ping when it's unhealthy, or nil.
+func (db *DB) HealthError() error {
+	db.health.mu.Lock()
+	defer db.health.mu.Unlock()
+	return db.health.lastErr
+}
+
+// Ping the db and update the health state.
+func (db *DB) checkHealth(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := db.DB.PingContext(ctx)
+
+	db.health.mu.Lock()
+	wasDown := db.health.down
+	db.health.down = err != nil
+	db.health.lastErr = err
+	db.health.mu.Unlock()
+
+	switch {
+	case err != nil && !wasDown:
+		log.Warn(ctx, "db is down", "section", db.Name(), "driver", db.DriverName(), "err", err)
+	case err == nil && wasDown:
+		log.Info(ctx, "db is up", "section", db.Name(), "driver", db.DriverName())
+	}
+	return err
+}
+
+// Checking the db is available for routing.
+func isHealthy(db *DB) bool {
+	return db.Healthy()
+}
@@ -0,0 +1,63 @@
+package qsql
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	l := &testLog{}
+	oriLog := log
+	SetLog(l)
+	defer SetLogger(oriLog)
+
+	dir := filepath.Join(t.TempDir(), "none")
+	replica, err := Open("sqlite", filepath.Join(dir, "replica.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica.setName("replica")
+	primary := openTestDB(t)
+	c := NewCluster(primary, replica)
+	defer c.Close()
+
+	if !replica.Healthy() {
+		t.Fatal("expect healthy before checking")
+	}
+	replica.StartHealthCheck(10 * time.Millisecond)
+	for i := 0; replica.Healthy(); i++ {
+		if i > 100 {
+			t.Fatal("expect unhealthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if replica.HealthError() == nil {
+		t.Fatal("expect the error of ping")
+	}
+	if c.Replica(context.TODO()) != primary {
+		t.Fatal("expect the primary when the replica is unhealthy")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; !replica.Healthy(); i++ {
+		if i > 100 {
+			t.Fatal("expect healthy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	replica.StopHealthCheck()
+	if replica.HealthError() != nil || c.Replica(context.TODO()) != replica {
+		t.Fatal("expect the replica when it's healthy")
+	}
+
+	if len(l.lines) != 2 || !strings.Contains(l.lines[0], "db is down") || !strings.Contains(l.lines[0], "section=replica") ||
+		!strings.Contains(l.lines[1], "db is up") {
+		t.Fatalf("unexpect transitions: %+v", l.lines)
+	}
+}
@@ -0,0 +1,112 @@
+package qsql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The operation is still running when closing the cache.
+type RunningOp struct {
+	Section string
+	Name    string
+	Sql     string
+	Args    []interface{} // redacted by the policy of db
+	Start   time.Time
+}
+
+type inflightOp struct {
+	start  time.Time
+	cancel context.CancelFunc // nil when it can not be canceled
+}
+
+// The in-flight operations of db, the Commit operation is running until the tx done.
+type dbInflight struct {
+	mu   sync.Mutex
+	ops  map[*Operation]inflightOp
+	idle chan struct{} // closed when all operations done
+}
+
+func (f *dbInflight) add(op *Operation, cancel context.CancelFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ops == nil {
+		f.ops = map[*Operation]inflightOp{}
+	}
+	if len(f.ops) == 0 {
+		f.idle = make(chan struct{})
+	}
+	f.ops[op] = inflightOp{start: time.Now(), cancel: cancel}
+}
+
+func (f *dbInflight) done(op *Operation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.ops[op]; !ok {
+		return
+	}
+	delete(f.ops, op)
+	if len(f.ops) == 0 {
+		close(f.idle)
+	}
+}
+
+// Return the channel closed when all operations done, nil if no operation is running.
+func (f *dbInflight) idleChan() chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.ops) == 0 {
+		return nil
+	}
+	return f.idle
+}
+
+// Cancel the context of the running operations.
+func (f *dbInflight) cancelAll() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.ops {
+		if o.cancel != nil {
+			o.cancel()
+		}
+	}
+}
+
+// Begin the operation with the query timeout and track it, the returned function must be called when the operation done.
+// The operation returned rows is running until the rows closed, the rows are read with the ctx after returned.
+func (db *DB) beginOp(ctx context.Context, op *Operation) (context.Context, func()) {
+	ctx, cancelTimeout := queryTimeoutCtx(db, ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	db.inflight.add(op, cancel)
+	done := func() {
+		db.inflight.done(op)
+		cancel()
+		cancelTimeout()
+	}
+	if !op.rowsOut {
+		return ctx, done
+	}
+	op.closeRows = done
+	return ctx, func() {
+		if op.closeRows != nil {
+			// no rows returned
+			op.closeRows()
+		}
+	}
+}
+
+func (db *DB) runningOps() []RunningOp {
+	db.inflight.mu.Lock()
+	defer db.inflight.mu.Unlock()
+	result := make([]RunningOp, 0, len(db.inflight.ops))
+	for op, o := range db.inflight.ops {
+		result = append(result, RunningOp{
+			Section: db.Name(),
+			Name:    op.Name,
+			Sql:     op.Sql,
+			Args:    op.RedactedArgs(),
+			Start:   o.start,
+		})
+	}
+	return result
+}
@@ -0,0 +1,96 @@
+package qsql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCloseCacheContext(t *testing.T) {
+	mdb := openTestDB(t)
+	RegCache("inflight", mdb)
+	defer func() {
+		CloseCache()
+		RegCacheWithIni("")
+	}()
+
+	// wait the running tx done
+	begun := make(chan struct{})
+	go func() {
+		GetCache("inflight").Commit(func(tx *Tx) error {
+			close(begun)
+			time.Sleep(20 * time.Millisecond)
+			_, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user")
+			return err
+		})
+	}()
+	<-begun
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	running, err := CloseCacheContext(ctx)
+	if err != nil || len(running) != 0 {
+		t.Fatalf("expect closed gracefully, but: %+v, %v", running, err)
+	}
+	if _, err := HasCache("inflight"); !ErrCacheClosing.Equal(err) {
+		t.Fatalf("expect closing, but: %v", err)
+	}
+	if !mdb.IsClose() {
+		t.Fatal("expect closed")
+	}
+
+	// force close the running tx
+	mdb = openTestDB(t)
+	RegCache("inflight", mdb)
+	begun, release, done := make(chan struct{}), make(chan struct{}), make(chan error)
+	go func() {
+		done <- GetCache("inflight").Commit(func(tx *Tx) error {
+			close(begun)
+			<-release
+			return nil
+		})
+	}()
+	<-begun
+	ctx, cancel = context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	running, err = CloseCacheContext(ctx)
+	if err == nil || len(running) != 1 || running[0].Name != "Commit" || running[0].Section != "inflight" {
+		t.Fatalf("expect the running commit, but: %+v, %v", running, err)
+	}
+	close(release)
+	if err := <-done; err == nil {
+		t.Fatal("expect the commit failed after closed")
+	}
+
+	// the query is running until the rows closed
+	mdb = openTestDB(t)
+	RegCache("inflight", mdb)
+	rows, err := GetCache("inflight").Query("SELECT * FROM user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		Close(rows)
+	}()
+	ctx, cancel = context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	running, err = CloseCacheContext(ctx)
+	if err != nil || len(running) != 0 {
+		t.Fatalf("expect closed after the rows closed, but: %+v, %v", running, err)
+	}
+
+	// force close the rows
+	mdb = openTestDB(t)
+	RegCache("inflight", mdb)
+	rows, err = GetCache("inflight").Query("SELECT * FROM user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(rows)
+	ctx, cancel = context.WithTimeout(context.TODO(), 20*time.Millisecond)
+	defer cancel()
+	running, err = CloseCacheContext(ctx)
+	if err == nil || len(running) != 1 || running[0].Name != "Query" {
+		t.Fatalf("expect the running query, but: %+v, %v", running, err)
+	}
+}
@@ -0,0 +1,121 @@
+package qsql
+
+import (
+	"context"
+	"time"
+)
+
+// The operation of qsql called, it's passed to the interceptors.
+type Operation struct {
+	// The name of operation, like "QueryStruct", "InsertStruct", "Exec", "Commit".
+	Name   string
+	Driver string
+	// The sql and args to execute, the interceptor can rewrite them before calling the next.
+	// DO NOT log the Args directly, using RedactedArgs instead.
+	Sql  string
+	Args []interface{}
+
+	// The fields are set after the operation done.
+	//
+	// The rows affected of exec, or the rows returned of query, 0 when unknow.
+	RowsAffected int64
+	Duration     time.Duration
+	Err          error
+
+	redact     *RedactPolicy
+	redactPos  []int  // the index of args to redact for the operation, like the 'redact' fields of InsertStruct
+	argOrigins []int  // the index of args before the IN expanded, nil if not expanded
+	rowsOut    bool   // the rows are read after the operation returned, like Query
+	closeRows  func() // called when the rows returned are closed, or when the operation done without rows
+}
+
+// Run the operation, it's the next of interceptor.
+type Invoker func(ctx context.Context, op *Operation) error
+
+// Intercept the operation, the interceptor must call the next to run the operation,
+// or return an error to stop it.
+//
+// Example for logging:
+//
+//	mdb.Use(func(ctx context.Context, op *qsql.Operation, next qsql.Invoker) error {
+//		err := next(ctx, op)
+//		log.Println(op.Name, op.Sql, op.RedactedArgs(), op.Duration, op.RowsAffected, err)
+//		return err
+//	})
+type Interceptor func(ctx context.Context, op *Operation, next Invoker) error
+
+// Register the interceptors to the db, they are called by the order of registered.
+// Every operation of the db will be intercepted, including the raw Exec, Query, and the operations of tx began by the db.
+func (db *DB) Use(interceptors ...Interceptor) {
+	db.setOptions(func(o *dbOptions) {
+		all := make([]Interceptor, 0, len(o.interceptors)+len(interceptors))
+		all = append(all, o.interceptors...)
+		o.interceptors = append(all, interceptors...)
+	})
+}
+
+func chainInterceptors(interceptors []Interceptor, invoke Invoker) Invoker {
+	for i := len(interceptors) - 1; i > -1; i-- {
+		interceptor, next := interceptors[i], invoke
+		invoke = func(ctx context.Context, op *Operation) error {
+			return interceptor(ctx, op, next)
+		}
+	}
+	return invoke
+}
+
+func (db *DB) run(ctx context.Context, op *Operation, fn func(ctx context.Context, conn sqlConn, op *Operation) error) error {
+	if tx := db.ctxTx(ctx); tx != nil {
+		return runOp(db, tx, ctx, tx.Tx, op, fn)
+	}
+	return runOp(db, nil, ctx, db.DB, op, fn)
+}
+
+func (tx *Tx) run(ctx context.Context, op *Operation, fn func(ctx context.Context, conn sqlConn, op *Operation) error) error {
+	return runOp(tx.db, tx, ctx, tx.Tx, op, fn)
+}
+
+// Run the operation with the hooks of db, the db and tx can be nil.
+func runOp(db *DB, tx *Tx, ctx context.Context, conn sqlConn, op *Operation, fn func(ctx context.Context, conn sqlConn, op *Operation) error) error {
+	if db != nil {
+		op.Sql = db.rebindSql(op.Sql)
+		// set before binding to redact the args of binding error
+		op.redact = db.options().redact
+	}
+	err := bindNamedOp(op)
+	if err == nil {
+		err = expandInOp(op)
+	}
+	if err != nil {
+		op.Err = wrapQueryError(op, err)
+		return op.Err
+	}
+	if db != nil && conn != nil && db.stmts.enabled() {
+		conn = &stmtConn{sqlConn: conn, db: db, tx: tx}
+	}
+	if db != nil {
+		var end func()
+		ctx, end = db.beginOp(ctx, op)
+		defer end()
+	}
+	invoke := func(ctx context.Context, op *Operation) error {
+		start := time.Now()
+		op.Err = fn(ctx, conn, op)
+		op.Duration = time.Since(start)
+		return op.Err
+	}
+	ctx, span := startSpan(db, tx, ctx, op)
+	if db == nil {
+		err = invoke(ctx, op)
+	} else {
+		err = chainInterceptors(db.options().interceptors, invoke)(ctx, op)
+	}
+	err = wrapQueryError(op, err)
+	op.Err = err
+	if db != nil {
+		logSlowQuery(ctx, db, op)
+		recordMetric(db, op)
+	}
+	endSpan(span, op)
+	return err
+}
@@ -0,0 +1,113 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	stderrors "errors"
+	"strings"
+	"testing"
+
+	"github.com/gwaylib/errors"
+)
+
+func TestInterceptor(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	errDenied := errors.New("denied")
+	ops := []*Operation{}
+	mdb.Use(func(ctx context.Context, op *Operation, next Invoker) error {
+		err := next(ctx, op)
+		ops = append(ops, op)
+		return err
+	}, func(ctx context.Context, op *Operation, next Invoker) error {
+		if strings.HasPrefix(op.Sql, "DELETE") {
+			return errDenied
+		}
+		return next(ctx, op)
+	})
+
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mdb.Commit(func(tx *Tx) error {
+		_, err := tx.Exec("INSERT INTO user (username) VALUES (?)", "t2")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	users := []txTestUser{}
+	if err := QueryStructs(mdb, &users, "SELECT * FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mdb.Exec("DELETE FROM user"); !errDenied.Equal(err) {
+		t.Fatalf("expect denied, but: %v", err)
+	}
+	count := 0
+	if err := mdb.QueryRow("DELETE FROM user RETURNING 1").Scan(&count); err == nil {
+		t.Fatal("expect denied")
+	}
+
+	names := []string{}
+	for _, op := range ops {
+		names = append(names, op.Name)
+	}
+	if strings.Join(names, ",") != "InsertStruct,Exec,Commit,QueryStructs,Exec,QueryRow" {
+		t.Fatalf("unexpect operations: %+v", names)
+	}
+	if ops[0].RowsAffected != 1 || ops[0].Driver != "sqlite" || !strings.HasPrefix(ops[0].Sql, "INSERT INTO user") {
+		t.Fatalf("unexpect insert operation: %+v", ops[0])
+	}
+	if ops[3].RowsAffected != 2 || ops[3].Duration <= 0 {
+		t.Fatalf("unexpect query operation: %+v", ops[3])
+	}
+}
+
+func TestInterceptorQueryRow(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	errDenied := errors.New("denied")
+	var last *Operation
+	mdb.Use(func(ctx context.Context, op *Operation, next Invoker) error {
+		last = op
+		if strings.HasPrefix(op.Sql, "DELETE") {
+			return errDenied
+		}
+		return next(ctx, op)
+	})
+
+	name := ""
+	if err := mdb.QueryRow("SELECT username FROM user WHERE id=?", 1).Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "t1" || last.Err != nil || last.RowsAffected != 1 {
+		t.Fatalf("unexpect operation: %s, %+v", name, last)
+	}
+
+	// the error of interceptor
+	count := 0
+	if err := mdb.QueryRow("DELETE FROM user RETURNING 1").Scan(&count); !errDenied.Equal(err) {
+		t.Fatalf("expect denied, but: %v", err)
+	}
+
+	// no data is seen by the interceptor, and the row keeps sql.ErrNoRows
+	if err := mdb.QueryRow("SELECT username FROM user WHERE id=?", 2).Scan(&name); err != sql.ErrNoRows {
+		t.Fatalf("expect sql.ErrNoRows, but: %v", err)
+	}
+	if !errors.ErrNoData.Equal(last.Err) || last.RowsAffected != 0 {
+		t.Fatalf("expect no data of operation, but: %+v", last)
+	}
+
+	// the error of driver
+	if err := mdb.QueryRow("SELECT username FROM unknow").Scan(&name); err == nil {
+		t.Fatal("expect error")
+	}
+	qErr := &QueryError{}
+	if !stderrors.As(last.Err, &qErr) || qErr.Op != "QueryRow" {
+		t.Fatalf("expect QueryError of operation, but: %v", last.Err)
+	}
+}
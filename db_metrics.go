@@ -0,0 +1,265 @@
+package qsql
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gwaylib/errors"
+)
+
+// the upper bounds in seconds of the latency histogram.
+var metricBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type metricKey struct {
+	db string
+	op string
+}
+
+type opMetric struct {
+	count   uint64
+	errors  uint64
+	sumNano uint64
+	buckets []uint64 // not cumulative, the last one is +Inf
+}
+
+var (
+	metricsLock = sync.RWMutex{}
+	metrics     = map[metricKey]*opMetric{}
+)
+
+func getOpMetric(key metricKey) *opMetric {
+	metricsLock.RLock()
+	m, ok := metrics[key]
+	metricsLock.RUnlock()
+	if ok {
+		return m
+	}
+
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	m, ok = metrics[key]
+	if !ok {
+		m = &opMetric{buckets: make([]uint64, len(metricBuckets)+1)}
+		metrics[key] = m
+	}
+	return m
+}
+
+// Record the operation of the named db, the db is named by the cache key.
+func recordMetric(db *DB, op *Operation) {
+	if db == nil {
+		return
+	}
+	name := db.Name()
+	if len(name) == 0 {
+		return
+	}
+	m := getOpMetric(metricKey{db: name, op: op.Name})
+	atomic.AddUint64(&m.count, 1)
+	if op.Err != nil && !errors.ErrNoData.Equal(op.Err) {
+		atomic.AddUint64(&m.errors, 1)
+	}
+	atomic.AddUint64(&m.sumNano, uint64(op.Duration))
+	seconds := op.Duration.Seconds()
+	idx := sort.SearchFloat64s(metricBuckets, seconds)
+	atomic.AddUint64(&m.buckets[idx], 1)
+}
+
+type opMetricSnapshot struct {
+	DB      string
+	Op      string
+	Count   uint64
+	Errors  uint64
+	Sum     float64  // seconds
+	Buckets []uint64 // cumulative, the last one is +Inf
+}
+
+func snapshotMetrics() []opMetricSnapshot {
+	metricsLock.RLock()
+	result := make([]opMetricSnapshot, 0, len(metrics))
+	for key, m := range metrics {
+		s := opMetricSnapshot{
+			DB:      key.db,
+			Op:      key.op,
+			Count:   atomic.LoadUint64(&m.count),
+			Errors:  atomic.LoadUint64(&m.errors),
+			Sum:     time.Duration(atomic.LoadUint64(&m.sumNano)).Seconds(),
+			Buckets: make([]uint64, len(m.buckets)),
+		}
+		total := uint64(0)
+		for i := range m.buckets {
+			total += atomic.LoadUint64(&m.buckets[i])
+			s.Buckets[i] = total
+		}
+		result = append(result, s)
+	}
+	metricsLock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DB != result[j].DB {
+			return result[i].DB < result[j].DB
+		}
+		return result[i].Op < result[j].Op
+	})
+	return result
+}
+
+// Return the named dbs of cache, including the replicas of cluster.
+func cachedDBs() []*DB {
+	cacheLock.Lock()
+	dbs := make([]*DB, 0, len(cache))
+	for _, db := range cache {
+		dbs = append(dbs, db)
+	}
+	for _, c := range clusterCache {
+		dbs = append(dbs, c.replicas...)
+	}
+	cacheLock.Unlock()
+
+	sort.Slice(dbs, func(i, j int) bool { return dbs[i].Name() < dbs[j].Name() })
+	return dbs
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Write the metrics of cached dbs in Prometheus text format.
+func WriteMetrics(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	ops := snapshotMetrics()
+
+	buf.WriteString("# HELP qsql_operations_total The total number of qsql operations.\n")
+	buf.WriteString("# TYPE qsql_operations_total counter\n")
+	for _, s := range ops {
+		fmt.Fprintf(buf, "qsql_operations_total{db=\"%s\",op=\"%s\"} %d\n", escapeLabel(s.DB), escapeLabel(s.Op), s.Count)
+	}
+	buf.WriteString("# HELP qsql_operation_errors_total The total number of failed qsql operations.\n")
+	buf.WriteString("# TYPE qsql_operation_errors_total counter\n")
+	for _, s := range ops {
+		fmt.Fprintf(buf, "qsql_operation_errors_total{db=\"%s\",op=\"%s\"} %d\n", escapeLabel(s.DB), escapeLabel(s.Op), s.Errors)
+	}
+	buf.WriteString("# HELP qsql_operation_duration_seconds The latency of qsql operations.\n")
+	buf.WriteString("# TYPE qsql_operation_duration_seconds histogram\n")
+	for _, s := range ops {
+		labels := fmt.Sprintf("db=\"%s\",op=\"%s\"", escapeLabel(s.DB), escapeLabel(s.Op))
+		for i, le := range metricBuckets {
+			fmt.Fprintf(buf, "qsql_operation_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, formatFloat(le), s.Buckets[i])
+		}
+		fmt.Fprintf(buf, "qsql_operation_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, s.Buckets[len(s.Buckets)-1])
+		fmt.Fprintf(buf, "qsql_operation_duration_seconds_sum{%s} %s\n", labels, formatFloat(s.Sum))
+		fmt.Fprintf(buf, "qsql_operation_duration_seconds_count{%s} %d\n", labels, s.Count)
+	}
+
+	dbs := cachedDBs()
+	stats := make([]struct {
+		name  string
+		stats map[string]float64
+	}, len(dbs))
+	for i, db := range dbs {
+		stats[i].name = db.Name()
+		stats[i].stats = poolStats(db)
+	}
+	for _, g := range poolGauges {
+		fmt.Fprintf(buf, "# HELP qsql_pool_%s %s\n", g.name, g.help)
+		fmt.Fprintf(buf, "# TYPE qsql_pool_%s %s\n", g.name, g.kind)
+		for _, s := range stats {
+			fmt.Fprintf(buf, "qsql_pool_%s{db=\"%s\"} %s\n", g.name, escapeLabel(s.name), formatFloat(s.stats[g.name]))
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var poolGauges = []struct {
+	name string
+	kind string
+	help string
+}{
+	{"max_open_connections", "gauge", "Maximum number of open connections to the database."},
+	{"open_connections", "gauge", "The number of established connections both in use and idle."},
+	{"in_use_connections", "gauge", "The number of connections currently in use."},
+	{"idle_connections", "gauge", "The number of idle connections."},
+	{"wait_count_total", "counter", "The total number of connections waited for."},
+	{"wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection."},
+	{"max_idle_closed_total", "counter", "The total number of connections closed due to SetMaxIdleConns."},
+	{"max_idle_time_closed_total", "counter", "The total number of connections closed due to SetConnMaxIdleTime."},
+	{"max_lifetime_closed_total", "counter", "The total number of connections closed due to SetConnMaxLifetime."},
+}
+
+func poolStats(db *DB) map[string]float64 {
+	s := db.Stats()
+	return map[string]float64{
+		"max_open_connections":        float64(s.MaxOpenConnections),
+		"open_connections":            float64(s.OpenConnections),
+		"in_use_connections":          float64(s.InUse),
+		"idle_connections":            float64(s.Idle),
+		"wait_count_total":            float64(s.WaitCount),
+		"wait_duration_seconds_total": s.WaitDuration.Seconds(),
+		"max_idle_closed_total":       float64(s.MaxIdleClosed),
+		"max_idle_time_closed_total":  float64(s.MaxIdleTimeClosed),
+		"max_lifetime_closed_total":   float64(s.MaxLifetimeClosed),
+	}
+}
+
+// Return a http.Handler to expose the metrics of cached dbs in Prometheus text format.
+//
+// Example:
+// http.Handle("/metrics/qsql", qsql.MetricsHandler())
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := WriteMetrics(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// Publish the metrics of cached dbs to expvar with the name, it should be called once.
+//
+// Example:
+// qsql.PublishExpvar("qsql")
+func PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		result := map[string]interface{}{}
+		for _, s := range snapshotMetrics() {
+			dbData, ok := result[s.DB].(map[string]interface{})
+			if !ok {
+				dbData = map[string]interface{}{}
+				result[s.DB] = dbData
+			}
+			ops, ok := dbData["ops"].(map[string]interface{})
+			if !ok {
+				ops = map[string]interface{}{}
+				dbData["ops"] = ops
+			}
+			ops[s.Op] = map[string]interface{}{
+				"count":       s.Count,
+				"errors":      s.Errors,
+				"sum_seconds": s.Sum,
+			}
+		}
+		for _, db := range cachedDBs() {
+			dbData, ok := result[db.Name()].(map[string]interface{})
+			if !ok {
+				dbData = map[string]interface{}{}
+				result[db.Name()] = dbData
+			}
+			dbData["pool"] = poolStats(db)
+		}
+		return result
+	}))
+}
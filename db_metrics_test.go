@@ -0,0 +1,50 @@
+package qsql
+
+import (
+	"expvar"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	mdb := openTestDB(t)
+	RegCache("metrics_test", mdb)
+	defer Close(mdb)
+
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	u := &txTestUser{}
+	if err := mdb.QueryStruct(u, "SELECT * FROM user WHERE id=?", 100); err == nil {
+		t.Fatal("expect no data")
+	}
+	if _, err := mdb.Exec("SELECT * FROM not_found"); err == nil {
+		t.Fatal("expect error")
+	}
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Result().Body)
+	output := string(body)
+	for _, expect := range []string{
+		`qsql_operations_total{db="metrics_test",op="InsertStruct"} 1`,
+		`qsql_operation_errors_total{db="metrics_test",op="QueryStruct"} 0`,
+		`qsql_operation_errors_total{db="metrics_test",op="Exec"} 1`,
+		`qsql_operation_duration_seconds_bucket{db="metrics_test",op="InsertStruct",le="+Inf"} 1`,
+		`qsql_operation_duration_seconds_count{db="metrics_test",op="QueryStruct"} 1`,
+		`# TYPE qsql_pool_open_connections gauge`,
+		`qsql_pool_max_open_connections{db="metrics_test"} 1`,
+	} {
+		if !strings.Contains(output, expect) {
+			t.Fatalf("expect '%s' in:\n%s", expect, output)
+		}
+	}
+
+	PublishExpvar("qsql_metrics_test")
+	vars := expvar.Get("qsql_metrics_test").String()
+	if !strings.Contains(vars, `"metrics_test":{"ops":{"Exec":{"count":1,"errors":1`) {
+		t.Fatalf("unexpect expvar: %s", vars)
+	}
+}
@@ -0,0 +1,46 @@
+package qsql
+
+import "sync"
+
+// The max count of the translated sql to cache, the cache is reset when it's full.
+const rebindCacheSize = 1024
+
+type dbRebind struct {
+	mu    sync.RWMutex
+	cache map[string]string
+}
+
+// Rebind the '?' placeholders of raw sql to the driver in every query helper and Exec, like the SelectBuilder.String(),
+// so the sql can be shared between the drivers, it's disabled by default.
+// The translated sql is cached by the sql.
+func (db *DB) SetRebind(rebind bool) {
+	db.setOptions(func(o *dbOptions) {
+		o.rebind = rebind
+	})
+}
+
+// Return the sql translated to the driver of db, it's the same sql when the rebind is disabled.
+func (db *DB) rebindSql(querySql string) string {
+	if !db.options().rebind {
+		return querySql
+	}
+	return db.rebinds.get(db.drvName, querySql)
+}
+
+func (r *dbRebind) get(drvName, querySql string) string {
+	r.mu.RLock()
+	result, ok := r.cache[querySql]
+	r.mu.RUnlock()
+	if ok {
+		return result
+	}
+
+	result = rebind(drvName, querySql)
+	r.mu.Lock()
+	if r.cache == nil || len(r.cache) >= rebindCacheSize {
+		r.cache = map[string]string{}
+	}
+	r.cache[querySql] = result
+	r.mu.Unlock()
+	return result
+}
@@ -0,0 +1,64 @@
+package qsql
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	cases := []struct {
+		drvName string
+		expect  string
+	}{
+		{DRV_NAME_MYSQL, "SELECT * FROM user WHERE id=? AND username=?"},
+		{DRV_NAME_POSTGRES, "SELECT * FROM user WHERE id=$1 AND username=$2"},
+		{DRV_NAME_ORACLE, "SELECT * FROM user WHERE id=:1 AND username=:2"},
+		{DRV_NAME_SQLSERVER, "SELECT * FROM user WHERE id=@p1 AND username=@p2"},
+	}
+	for _, c := range cases {
+		if result := rebind(c.drvName, "SELECT * FROM user WHERE id=? AND username=?"); result != c.expect {
+			t.Fatalf("%s: expect %s, but: %s", c.drvName, c.expect, result)
+		}
+	}
+}
+
+func TestDBRebind(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.Exec("INSERT INTO user (username) VALUES (?)", "t1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// sqlite supports the '$n' placeholders of postgres
+	pdb := newDB(DRV_NAME_POSTGRES, mdb.DB)
+	sqls := []string{}
+	pdb.Use(func(ctx context.Context, op *Operation, next Invoker) error {
+		sqls = append(sqls, op.Sql)
+		return next(ctx, op)
+	})
+
+	count := 0
+	querySql := "SELECT COUNT(*) FROM user WHERE id=? AND username=?"
+	if err := pdb.QueryElem(&count, querySql, 1, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if sqls[0] != querySql {
+		t.Fatalf("expect not rebind, but: %s", sqls[0])
+	}
+
+	pdb.SetRebind(true)
+	for i := 0; i < 2; i++ {
+		if err := pdb.QueryElem(&count, querySql, 1, "t1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := pdb.Exec("UPDATE user SET username=? WHERE id=?", "t2", 1); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || sqls[1] != "SELECT COUNT(*) FROM user WHERE id=$1 AND username=$2" || sqls[3] != "UPDATE user SET username=$1 WHERE id=$2" {
+		t.Fatalf("unexpect rebind: %d, %+v", count, sqls)
+	}
+	if len(pdb.rebinds.cache) != 2 {
+		t.Fatalf("expect 2 cached sql, but: %+v", pdb.rebinds.cache)
+	}
+}
@@ -0,0 +1,307 @@
+package qsql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// The value to replace the redacted arg.
+const REDACTED = "******"
+
+// The policy to redact the args before they are written to the errors, logs and dumps,
+// the args to execute are not changed.
+type RedactPolicy struct {
+	// The index of args to redact for every sql, start from 0,
+	// it's the index of the args passed in, all the elements of a slice arg expanded for 'IN (?)' are redacted.
+	Positions []int
+	// The column names to redact, it's case-insensitive,
+	// matched with the column before the placeholder like "password" of "password=?",
+	// or the column of the same position in "INSERT INTO t (name, password) VALUES (?, ?)".
+	Columns []string
+}
+
+var redactHashAll atomic.Bool
+
+// Replace all the args with a short sha256 hash for all dbs, the same value has the same hash to trace.
+// It takes precedence over the RedactPolicy of db.
+func SetRedactHashAll(hashAll bool) {
+	redactHashAll.Store(hashAll)
+}
+
+// Set the redact policy of db, nil to disable.
+// The field of InsertStruct with the 'redact' tag option like `db:"password,redact"` is always redacted.
+func (db *DB) SetRedactPolicy(p *RedactPolicy) {
+	db.setOptions(func(o *dbOptions) {
+		o.redact = p
+	})
+}
+
+// Return the args redacted by the policy of db, using it for logging instead of the Args.
+func (op *Operation) RedactedArgs() []interface{} {
+	p, positions := op.redact, op.redactPos
+	if op.argOrigins != nil {
+		// the positions are the index of args before the IN expanded
+		positions = expandPositions(positions, op.argOrigins)
+		if p != nil && len(p.Positions) > 0 {
+			expanded := *p
+			expanded.Positions = expandPositions(p.Positions, op.argOrigins)
+			p = &expanded
+		}
+	}
+	return redactArgs(p, op.Driver, op.Sql, op.Args, positions)
+}
+
+// Return the index of expanded args which are from the positions.
+func expandPositions(positions, origins []int) []int {
+	if len(positions) == 0 {
+		return positions
+	}
+	result := []int{}
+	for i, origin := range origins {
+		for _, pos := range positions {
+			if pos == origin {
+				result = append(result, i)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func hashArg(arg interface{}) string {
+	var b []byte
+	switch v := arg.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		b = []byte(fmt.Sprint(v))
+	}
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Return a copy of args with the redacted values, or the args if nothing to redact.
+func redactArgs(p *RedactPolicy, drvName, querySql string, args []interface{}, positions []int) []interface{} {
+	if len(args) == 0 {
+		return args
+	}
+	if redactHashAll.Load() {
+		result := make([]interface{}, len(args))
+		for i, arg := range args {
+			result[i] = hashArg(arg)
+		}
+		return result
+	}
+	if p == nil && len(positions) == 0 {
+		return args
+	}
+
+	redacts := make([]bool, len(args))
+	mark := func(idx int) {
+		if idx >= 0 && idx < len(redacts) {
+			redacts[idx] = true
+		}
+	}
+	for _, idx := range positions {
+		mark(idx)
+	}
+	if p != nil {
+		for _, idx := range p.Positions {
+			mark(idx)
+		}
+		if len(p.Columns) > 0 && redactNamedArg(p, drvName, querySql, args) {
+			// the named arg is not bound when the binding failed
+			result := make([]interface{}, len(args))
+			copy(result, args)
+			result[0] = redactNamedValue(p, args[0])
+			return result
+		}
+		if len(p.Columns) > 0 {
+			for idx, column := range placeholderColumns(drvName, querySql) {
+				for _, c := range p.Columns {
+					if len(column) > 0 && strings.EqualFold(column, c) {
+						mark(idx)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	result := make([]interface{}, len(args))
+	for i, arg := range args {
+		if redacts[i] {
+			result[i] = REDACTED
+		} else {
+			result[i] = arg
+		}
+	}
+	return result
+}
+
+// Checking the args is a named arg which is not bound to the sql, and not redacted by the positions.
+func redactNamedArg(p *RedactPolicy, drvName, querySql string, args []interface{}) bool {
+	for _, idx := range p.Positions {
+		if idx == 0 {
+			return false
+		}
+	}
+	if _, ok := namedArgOf(args); !ok {
+		return false
+	}
+	return hasNamed(drvName, querySql)
+}
+
+// Redact the keys of map matched the columns, or the whole value of other named arg.
+func redactNamedValue(p *RedactPolicy, arg interface{}) interface{} {
+	m, ok := arg.(map[string]interface{})
+	if !ok {
+		return REDACTED
+	}
+	result := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		result[key] = val
+		for _, c := range p.Columns {
+			if strings.EqualFold(key, c) {
+				result[key] = REDACTED
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Return the column name of every placeholder by the index of args, it's empty when unknow.
+func placeholderColumns(drvName, querySql string) map[int]string {
+	toks := scanRedactTokens(drvName, querySql)
+	result := map[int]string{}
+
+	// INSERT INTO t (a, b) VALUES (?, ?), (?, ?)
+	if len(toks) > 0 && strings.EqualFold(toks[0].text, "INSERT") {
+		columns, inColumns, isValues, depth, value := []string{}, false, false, 0, 0
+		for i, tok := range toks {
+			switch {
+			case tok.ident && strings.EqualFold(tok.text, "VALUES"):
+				isValues = true
+			case !isValues && len(columns) == 0 && tok.text == "(" && i > 0:
+				inColumns = true
+			case inColumns && tok.text == ")":
+				inColumns = false
+			case inColumns && tok.ident:
+				columns = append(columns, tok.text)
+			case isValues && tok.text == "(":
+				if depth == 0 {
+					value = 0
+				}
+				depth++
+			case isValues && tok.text == ")":
+				depth--
+			case isValues && depth == 1 && tok.text == ",":
+				value++
+			case isValues && depth > 0 && tok.arg >= 0 && value < len(columns):
+				result[tok.arg] = columns[value]
+			}
+		}
+		return result
+	}
+
+	// the identifier before the placeholder, like "a=?", "a IN (?, ?)", "a NOT LIKE ?"
+	for i, tok := range toks {
+		if tok.arg < 0 {
+			continue
+		}
+	walk:
+		for j := i - 1; j >= 0; j-- {
+			prev := toks[j]
+			switch {
+			case prev.arg >= 0:
+				// the same column of the list
+				if column, ok := result[prev.arg]; ok {
+					result[tok.arg] = column
+				}
+				break walk
+			case prev.ident:
+				switch strings.ToUpper(prev.text) {
+				case "LIKE", "ILIKE", "NOT", "IN", "IS":
+					continue
+				}
+				result[tok.arg] = prev.text
+				break walk
+			case strings.Contains("=<>!(,", prev.text):
+				continue
+			default:
+				break walk
+			}
+		}
+	}
+	return result
+}
+
+type redactToken struct {
+	text  string
+	ident bool
+	arg   int // the index of arg when it's a placeholder, or -1
+}
+
+func scanRedactTokens(drvName, s string) []redactToken {
+	isIdent := func(c byte) bool {
+		return c == '.' || c == '$' || isSqlIdent(c)
+	}
+	unqualify := func(name string) string {
+		return name[strings.LastIndex(name, ".")+1:]
+	}
+
+	toks := []redactToken{}
+	argIdx := 0
+	for _, lexTok := range lexSql(drvName, s) {
+		text := lexTok.text
+		switch lexTok.kind {
+		case sqlString:
+			toks = append(toks, redactToken{text: "'", arg: -1})
+		case sqlQuotedIdent:
+			toks = append(toks, redactToken{text: text[1 : len(text)-1], ident: true, arg: -1})
+		case sqlComment:
+			// ignore
+		case sqlPlaceholder:
+			if text == "?" {
+				toks = append(toks, redactToken{text: text, arg: argIdx})
+				argIdx++
+				continue
+			}
+			// $1, :1, @p1, ?1
+			n, _ := strconv.Atoi(strings.TrimLeft(text, "?$:@pP"))
+			toks = append(toks, redactToken{text: text, arg: n - 1})
+		case sqlNamed:
+			toks = append(toks, redactToken{text: text, arg: -1})
+		default:
+			for i := 0; i < len(text); {
+				c := text[i]
+				switch {
+				case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+					i++
+				case isIdent(c):
+					j := i
+					for j < len(text) && isIdent(text[j]) {
+						j++
+					}
+					if isSqlDigit(c) {
+						toks = append(toks, redactToken{text: text[i:j], arg: -1})
+					} else {
+						toks = append(toks, redactToken{text: unqualify(text[i:j]), ident: true, arg: -1})
+					}
+					i = j
+				default:
+					toks = append(toks, redactToken{text: string(c), arg: -1})
+					i++
+				}
+			}
+		}
+	}
+	return toks
+}
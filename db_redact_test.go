@@ -0,0 +1,146 @@
+package qsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholderColumns(t *testing.T) {
+	cases := []struct {
+		drvName string
+		sql     string
+		expect  map[int]string
+	}{
+		{DRV_NAME_MYSQL, "SELECT * FROM user WHERE username=? AND passwd = ?", map[int]string{0: "username", 1: "passwd"}},
+		{DRV_NAME_MYSQL, "SELECT * FROM user u WHERE u.passwd<>? OR u.`token` NOT LIKE ?", map[int]string{0: "passwd", 1: "token"}},
+		{DRV_NAME_MYSQL, "SELECT * FROM user WHERE id IN (?, ?) AND note='a=?'", map[int]string{0: "id", 1: "id"}},
+		{DRV_NAME_POSTGRES, "UPDATE user SET passwd=$2 WHERE id=$1", map[int]string{1: "passwd", 0: "id"}},
+		{DRV_NAME_SQLSERVER, "UPDATE [user] SET [passwd]=@p1 WHERE id=@p2", map[int]string{0: "passwd", 1: "id"}},
+		{DRV_NAME_SQLITE3, `INSERT INTO user ("username", "passwd") VALUES (?, ?), (?, ?)`, map[int]string{0: "username", 1: "passwd", 2: "username", 3: "passwd"}},
+		{DRV_NAME_ORACLE, "INSERT INTO user (username, passwd, created) VALUES (lower(?), :2, NOW())", map[int]string{0: "username", 1: "passwd"}},
+	}
+	for _, c := range cases {
+		columns := placeholderColumns(c.drvName, c.sql)
+		if len(columns) != len(c.expect) {
+			t.Fatalf("%s: expect %+v, but: %+v", c.sql, c.expect, columns)
+		}
+		for idx, column := range c.expect {
+			if columns[idx] != column {
+				t.Fatalf("%s: expect %+v, but: %+v", c.sql, c.expect, columns)
+			}
+		}
+	}
+}
+
+type redactTestUser struct {
+	ID       int64  `db:"id,auto_increment"`
+	UserName string `db:"username"`
+	Passwd   string `db:"passwd,redact"`
+}
+
+func TestRedact(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.Exec("ALTER TABLE user ADD COLUMN passwd TEXT"); err != nil {
+		t.Fatal(err)
+	}
+
+	redacted := [][]interface{}{}
+	mdb.Use(func(ctx context.Context, op *Operation, next Invoker) error {
+		redacted = append(redacted, op.RedactedArgs())
+		return next(ctx, op)
+	})
+	if _, err := mdb.InsertStruct(&redactTestUser{UserName: "t1", Passwd: "secret"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if args := redacted[0]; len(args) != 2 || args[0] != "t1" || args[1] != REDACTED {
+		t.Fatalf("unexpect insert args: %+v", args)
+	}
+
+	mdb.SetRedactPolicy(&RedactPolicy{Columns: []string{"PASSWD"}})
+	count := 0
+	err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM unknow WHERE username=? AND passwd=?", "t1", "secret")
+	if err == nil {
+		t.Fatal("expect error")
+	}
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), REDACTED) || !strings.Contains(err.Error(), "t1") {
+		t.Fatalf("unexpect error: %s", err.Error())
+	}
+
+	mdb.SetRedactPolicy(&RedactPolicy{Positions: []int{0}})
+	if args := redactArgs(mdb.options().redact, mdb.DriverName(), "SELECT ?, ?", []interface{}{"a", "b"}, nil); args[0] != REDACTED || args[1] != "b" {
+		t.Fatalf("unexpect position args: %+v", args)
+	}
+
+	SetRedactHashAll(true)
+	defer SetRedactHashAll(false)
+	args := redactArgs(nil, "", "SELECT ?, ?, ?", []interface{}{"a", "a", 1}, nil)
+	if args[0] != args[1] || args[0] == args[2] || !strings.HasPrefix(args[0].(string), "sha256:") {
+		t.Fatalf("unexpect hash args: %+v", args)
+	}
+	l := &testLog{}
+	oriLog := log
+	SetLog(l)
+	defer SetLogger(oriLog)
+	_ = NewSelectBuidler(mdb).SetDump(true).Select("id").From("user").Where("passwd=?", "secret").String()
+	if len(l.lines) != 1 || strings.Contains(l.lines[0], "secret") || !strings.Contains(l.lines[0], "sha256:") {
+		t.Fatalf("unexpect dump: %+v", l.lines)
+	}
+}
+
+func TestRedactExpandIn(t *testing.T) {
+	for _, c := range []struct {
+		positions []int
+		redactPos []int
+		expect    string
+	}{
+		{[]int{1}, nil, "[1 2 3 ******]"},
+		{[]int{0}, nil, "[****** ****** ****** topsecret]"},
+		{nil, []int{1}, "[1 2 3 ******]"},
+	} {
+		op := &Operation{
+			Driver:    DRV_NAME_MYSQL,
+			Sql:       "SELECT * FROM user WHERE id IN (?) AND secret = ?",
+			Args:      []interface{}{[]int{1, 2, 3}, "topsecret"},
+			redact:    &RedactPolicy{Positions: c.positions},
+			redactPos: c.redactPos,
+		}
+		if err := expandInOp(op); err != nil {
+			t.Fatal(err)
+		}
+		if args := fmt.Sprint(op.RedactedArgs()); args != c.expect {
+			t.Fatalf("%+v: expect %s, but: %s", c.positions, c.expect, args)
+		}
+	}
+}
+
+func TestRedactBindError(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	count := 0
+	for _, p := range []*RedactPolicy{
+		{Columns: []string{"passwd"}, Positions: []int{0, 1}},
+		{Columns: []string{"passwd"}},
+	} {
+		mdb.SetRedactPolicy(p)
+		// the named arg not found
+		err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE passwd=:passwd AND username=:missing", map[string]interface{}{"passwd": "topsecret"})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if strings.Contains(err.Error(), "topsecret") {
+			t.Fatalf("unexpect error: %s", err.Error())
+		}
+		// the empty IN
+		err = mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE passwd=? AND id IN (?)", "topsecret", []int{})
+		if err == nil {
+			t.Fatal("expect error")
+		}
+		if strings.Contains(err.Error(), "topsecret") {
+			t.Fatalf("unexpect error: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/gwaylib/errors"
+)
+
+var (
+	errProxyNotSupported = errors.New("not supported by the proxy driver")
+
+	// The sql.Row can not be made out of the sql package,
+	// so the result of operation is returned by the db of the proxy driver.
+	proxyDB = sql.OpenDB(proxyConnector{})
+)
+
+// The result of operation passed to the proxy driver as the only one arg.
+type proxyResult struct {
+	rows driver.Rows
+	err  error
+}
+
+type proxyConnector struct{}
+
+func (c proxyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return proxyConn{}, nil
+}
+
+func (c proxyConnector) Driver() driver.Driver {
+	return proxyDriver{}
+}
+
+type proxyDriver struct{}
+
+func (d proxyDriver) Open(name string) (driver.Conn, error) {
+	return proxyConn{}, nil
+}
+
+type proxyConn struct{}
+
+func (c proxyConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errProxyNotSupported
+}
+
+func (c proxyConn) Close() error {
+	return nil
+}
+
+func (c proxyConn) Begin() (driver.Tx, error) {
+	return nil, errProxyNotSupported
+}
+
+// Accept the *proxyResult arg.
+func (c proxyConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return nil
+}
+
+func (c proxyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, errProxyNotSupported
+	}
+	result, ok := args[0].Value.(*proxyResult)
+	if !ok {
+		return nil, errProxyNotSupported
+	}
+	return result.rows, result.err
+}
+
+// The row read before the operation done, it's empty when no data.
+type bufferedRow struct {
+	columns []string
+	values  []driver.Value
+	read    bool
+}
+
+// Read the first row of rows and close it, return errors.ErrNoData when no data.
+func readBufferedRow(rows *sql.Rows) (*bufferedRow, error) {
+	defer Close(rows)
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	row := &bufferedRow{columns: columns}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return row, errors.ErrNoData
+	}
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	if err := rows.Scan(values...); err != nil {
+		return nil, err
+	}
+	row.values = make([]driver.Value, len(columns))
+	for i, v := range values {
+		row.values[i] = *(v.(*interface{}))
+	}
+	return row, rows.Close()
+}
+
+func (r *bufferedRow) Columns() []string {
+	return r.columns
+}
+
+func (r *bufferedRow) Close() error {
+	return nil
+}
+
+func (r *bufferedRow) Next(dest []driver.Value) error {
+	if r.read || r.values == nil {
+		return io.EOF
+	}
+	r.read = true
+	copy(dest, r.values)
+	return nil
+}
+
+// Make the sql.Row of the buffered row, or of the error when the row is nil.
+func makeRow(row *bufferedRow, err error) *sql.Row {
+	if row == nil {
+		return proxyDB.QueryRowContext(context.Background(), "", &proxyResult{err: err})
+	}
+	return proxyDB.QueryRowContext(context.Background(), "", &proxyResult{rows: row})
+}
+
+// Return the rows calling the closeRows of operation when closed, it's the rows self when no closeRows.
+func takeRows(op *Operation, rows *sql.Rows) (*sql.Rows, error) {
+	onClose := op.closeRows
+	if onClose == nil {
+		return rows, nil
+	}
+	op.closeRows = nil
+	result, err := proxyDB.QueryContext(context.Background(), "", &proxyResult{rows: &proxyRows{rows: rows, onClose: onClose}})
+	if err != nil {
+		rows.Close()
+		onClose()
+		return nil, err
+	}
+	return result, nil
+}
+
+// The driver rows read from the sql.Rows of operation.
+type proxyRows struct {
+	rows      *sql.Rows
+	values    []interface{}
+	types     []*sql.ColumnType
+	onClose   func()
+	closeOnce sync.Once
+
+	peeked  bool // the next result set is checked
+	hasNext bool
+}
+
+func (r *proxyRows) Columns() []string {
+	columns, _ := r.rows.Columns()
+	return columns
+}
+
+func (r *proxyRows) Close() error {
+	err := r.rows.Close()
+	r.closeOnce.Do(r.onClose)
+	return err
+}
+
+func (r *proxyRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	if len(r.values) != len(dest) {
+		r.values = make([]interface{}, len(dest))
+		for i := range r.values {
+			r.values[i] = new(interface{})
+		}
+	}
+	if err := r.rows.Scan(r.values...); err != nil {
+		return err
+	}
+	for i, v := range r.values {
+		dest[i] = *(v.(*interface{}))
+	}
+	return nil
+}
+
+// The next result set is moved to when checking, it's called after the current one read out.
+func (r *proxyRows) HasNextResultSet() bool {
+	if !r.peeked {
+		r.peeked, r.hasNext = true, r.rows.NextResultSet()
+	}
+	return r.hasNext
+}
+
+func (r *proxyRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	r.peeked, r.types = false, nil
+	return nil
+}
+
+func (r *proxyRows) columnType(index int) *sql.ColumnType {
+	if r.types == nil {
+		r.types, _ = r.rows.ColumnTypes()
+	}
+	if index >= len(r.types) {
+		return nil
+	}
+	return r.types[index]
+}
+
+func (r *proxyRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct := r.columnType(index); ct != nil && ct.ScanType() != nil {
+		return ct.ScanType()
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *proxyRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct := r.columnType(index); ct != nil {
+		return ct.DatabaseTypeName()
+	}
+	return ""
+}
+
+func (r *proxyRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct := r.columnType(index); ct != nil {
+		return ct.Length()
+	}
+	return 0, false
+}
+
+func (r *proxyRows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct := r.columnType(index); ct != nil {
+		return ct.Nullable()
+	}
+	return false, false
+}
+
+func (r *proxyRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct := r.columnType(index); ct != nil {
+		return ct.DecimalSize()
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,230 @@
+package qsql
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gwaylib/errors"
+)
+
+// Return the shard index of the key in [0, n).
+type ShardFunc func(key interface{}, n int) (int, error)
+
+func shardHash(key interface{}) (uint64, error) {
+	v := reflect.ValueOf(key)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// the negative key is hashed by the bits of two's complement, -1 and 1 are different.
+		return uint64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.String:
+		return uint64(crc32.ChecksumIEEE([]byte(v.String()))), nil
+	case reflect.Slice:
+		if b, ok := key.([]byte); ok {
+			return uint64(crc32.ChecksumIEEE(b)), nil
+		}
+	}
+	return 0, errors.New("unsupport shard key").As(fmt.Sprintf("%T", key))
+}
+
+// Shard the key by modulo, the string key is hashed by crc32 before.
+func ShardModulo(key interface{}, n int) (int, error) {
+	h, err := shardHash(key)
+	if err != nil {
+		return 0, errors.As(err)
+	}
+	return int(h % uint64(n)), nil
+}
+
+// Shard the key by consistent hash, vnodes is the virtual nodes of every shard, default is 160.
+func NewShardConsistentHash(vnodes int) ShardFunc {
+	if vnodes <= 0 {
+		vnodes = 160
+	}
+	type ring struct {
+		hashes []uint32
+		shards map[uint32]int
+	}
+	rings := sync.Map{} // the ring is cached by number of shards
+	getRing := func(n int) *ring {
+		if r, ok := rings.Load(n); ok {
+			return r.(*ring)
+		}
+		r := &ring{shards: map[uint32]int{}}
+		for i := 0; i < n; i++ {
+			for j := 0; j < vnodes; j++ {
+				h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + "#" + strconv.Itoa(j)))
+				if _, ok := r.shards[h]; ok {
+					continue
+				}
+				r.shards[h] = i
+				r.hashes = append(r.hashes, h)
+			}
+		}
+		sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+		rings.Store(n, r)
+		return r
+	}
+	return func(key interface{}, n int) (int, error) {
+		var h uint32
+		if b, ok := key.([]byte); ok {
+			h = crc32.ChecksumIEEE(b)
+		} else {
+			h = crc32.ChecksumIEEE([]byte(fmt.Sprint(key)))
+		}
+		r := getRing(n)
+		idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+		if idx == len(r.hashes) {
+			idx = 0
+		}
+		return r.shards[r.hashes[idx]], nil
+	}
+}
+
+// Shard the integer key by a range table,
+// the key is in shard i when key < upperBounds[i], and the last shard includes others.
+//
+// Example, [0,1000) is in shard 0, [1000, 2000) in shard 1, [2000, ∞) in shard 2:
+// qsql.NewShardRange(1000, 2000)
+func NewShardRange(upperBounds ...int64) ShardFunc {
+	return func(key interface{}, n int) (int, error) {
+		v := reflect.ValueOf(key)
+		var k int64
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			k = v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			k = int64(v.Uint())
+		default:
+			return 0, errors.New("unsupport shard key").As(fmt.Sprintf("%T", key))
+		}
+		idx := sort.Search(len(upperBounds), func(i int) bool { return k < upperBounds[i] })
+		if idx >= n {
+			return 0, errors.New("shard out of range").As(key, n)
+		}
+		return idx, nil
+	}
+}
+
+// qsql.Shard routes the shard key to a db of cache.
+type Shard struct {
+	sections []string
+	fn       ShardFunc
+}
+
+// Make a shard with the cache sections, the index of sections is the shard index.
+func NewShard(fn ShardFunc, sections ...string) *Shard {
+	if len(sections) == 0 {
+		panic("need sections of shard")
+	}
+	return &Shard{
+		sections: sections,
+		fn:       fn,
+	}
+}
+
+// Make a shard with the sections like 'user_0', 'user_1'...'user_{n-1}' when the prefix is 'user_'.
+func NewShardWithPrefix(fn ShardFunc, prefix string, n int) *Shard {
+	sections := make([]string, n)
+	for i := 0; i < n; i++ {
+		sections[i] = prefix + strconv.Itoa(i)
+	}
+	return NewShard(fn, sections...)
+}
+
+func (s *Shard) Sections() []string {
+	return s.sections
+}
+
+// Return the cache section of the key
+func (s *Shard) Section(key interface{}) (string, error) {
+	idx, err := s.fn(key, len(s.sections))
+	if err != nil {
+		return "", errors.As(err, key)
+	}
+	if idx < 0 || idx >= len(s.sections) {
+		return "", errors.New("shard out of range").As(key, idx)
+	}
+	return s.sections[idx], nil
+}
+
+// Return the db of the key
+func (s *Shard) Get(key interface{}) (*DB, error) {
+	section, err := s.Section(key)
+	if err != nil {
+		return nil, errors.As(err)
+	}
+	db, err := getCache(section)
+	if err != nil {
+		return nil, errors.As(err, section)
+	}
+	return db, nil
+}
+
+// Return the dbs of all shards
+func (s *Shard) GetAll() ([]*DB, error) {
+	dbs := make([]*DB, len(s.sections))
+	for i, section := range s.sections {
+		db, err := getCache(section)
+		if err != nil {
+			return nil, errors.As(err, section)
+		}
+		dbs[i] = db
+	}
+	return dbs, nil
+}
+
+// Run QueryStructs on every shard concurrently, and merge the results by the order of shards.
+// the others will be canceled when one of them failed.
+func (s *Shard) QueryStructsAll(ctx context.Context, structsPtr interface{}, querySql string, args ...interface{}) error {
+	value := reflect.ValueOf(structsPtr)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice {
+		return errors.New("must pass a pointer of slice").As(value.Kind().String())
+	}
+	dbs, err := s.GetAll()
+	if err != nil {
+		return errors.As(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]reflect.Value, len(dbs))
+	errs := make([]error, len(dbs))
+	wg := sync.WaitGroup{}
+	for i, db := range dbs {
+		results[i] = reflect.New(value.Elem().Type())
+		wg.Add(1)
+		go func(i int, db *DB) {
+			defer wg.Done()
+			if err := db.QueryStructsContext(ctx, results[i].Interface(), querySql, args...); err != nil {
+				// keep the *QueryError of the shard to be unwrapped
+				errs[i] = fmt.Errorf("shard %s: %w", s.sections[i], err)
+				cancel()
+			}
+		}(i, db)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !stderrors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	direct := value.Elem()
+	for _, r := range results {
+		direct.Set(reflect.AppendSlice(direct, r.Elem()))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package qsql
+
+import (
+	"context"
+	stderrors "errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type shardTestUser struct {
+	ID       int64  `db:"id"`
+	UserName string `db:"username"`
+}
+
+func TestShardFunc(t *testing.T) {
+	if idx, _ := ShardModulo(int64(17), 16); idx != 1 {
+		t.Fatalf("expect 1, but: %d", idx)
+	}
+	if idx, _ := ShardModulo(int64(-1), 16); idx != 15 {
+		t.Fatalf("expect 15 of the two's complement, but: %d", idx)
+	}
+	if idx1, _ := ShardModulo(-3, 4); idx1 == 3 {
+		t.Fatal("expect -3 is not folded to 3")
+	}
+	if _, err := ShardModulo(1.1, 16); err == nil {
+		t.Fatal("expect unsupport float key")
+	}
+	rangeFn := NewShardRange(1000, 2000)
+	for key, expect := range map[int]int{0: 0, 999: 0, 1000: 1, 2000: 2, 99999: 2} {
+		if idx, _ := rangeFn(key, 3); idx != expect {
+			t.Fatalf("expect %d of %d, but: %d", expect, key, idx)
+		}
+	}
+	hashFn := NewShardConsistentHash(0)
+	idx1, _ := hashFn("user-1", 16)
+	idx2, _ := hashFn("user-1", 16)
+	if idx1 != idx2 || idx1 < 0 || idx1 >= 16 {
+		t.Fatalf("expect stable index, but: %d, %d", idx1, idx2)
+	}
+}
+
+func TestShard(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		db, err := Open("sqlite", filepath.Join(dir, "user_"+strconv.Itoa(i)+".db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`CREATE TABLE user ("id" INTEGER PRIMARY KEY, "username" VARCHAR(32))`); err != nil {
+			t.Fatal(err)
+		}
+		RegCache("shard_user_"+strconv.Itoa(i), db)
+	}
+	defer CloseCache()
+
+	shard := NewShardWithPrefix(ShardModulo, "shard_user_", 2)
+	for id := int64(1); id <= 5; id++ {
+		db, err := shard.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.InsertStruct(&shardTestUser{ID: id, UserName: "u" + strconv.FormatInt(id, 10)}, "user"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db0 := GetCache("shard_user_0")
+	count := 0
+	if err := db0.QueryElem(&count, "SELECT COUNT(*) FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expect 2 users in shard 0, but: %d", count)
+	}
+
+	users := []shardTestUser{}
+	if err := shard.QueryStructsAll(context.TODO(), &users, "SELECT * FROM user ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 5 || users[0].ID != 2 || users[2].ID != 1 {
+		t.Fatalf("expect merged by shard order, but: %+v", users)
+	}
+	err := shard.QueryStructsAll(context.TODO(), &users, "SELECT * FROM not_found")
+	qErr := &QueryError{}
+	if !stderrors.As(err, &qErr) || qErr.Sql != "SELECT * FROM not_found" {
+		t.Fatalf("expect QueryError, but: %v", err)
+	}
+	if !strings.Contains(err.Error(), "shard_user_") {
+		t.Fatalf("expect the section in error, but: %v", err)
+	}
+}
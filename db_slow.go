@@ -0,0 +1,53 @@
+package qsql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var qsqlSrcDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// Return the first caller out of qsql package.
+func callerOutside() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != qsqlSrcDir || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", filepath.Base(frame.File), frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// Log the query which takes longer than the threshold through the qsql.Logger with sql, args, duration and caller.
+// It's disabled when the threshold is 0, that's the default.
+func (db *DB) SetSlowQuery(threshold time.Duration) {
+	db.setOptions(func(o *dbOptions) {
+		o.slowQuery = threshold
+	})
+}
+
+func logSlowQuery(ctx context.Context, db *DB, op *Operation) {
+	if db == nil || len(op.Sql) == 0 {
+		return
+	}
+	threshold := db.options().slowQuery
+	if threshold <= 0 || op.Duration < threshold {
+		return
+	}
+	log.Warn(ctx, "slow query",
+		"section", db.Name(), "driver", op.Driver, "op", op.Name, "duration", op.Duration,
+		"caller", callerOutside(), "sql", op.Sql, "args", op.RedactedArgs(),
+	)
+}
@@ -0,0 +1,48 @@
+package qsql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testLog struct {
+	lines []string
+}
+
+func (l *testLog) Println(msg ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintln(msg...))
+}
+
+func TestSlowQuery(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	l := &testLog{}
+	oriLog := log
+	SetLog(l)
+	defer SetLogger(oriLog)
+
+	count := 0
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>?", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.lines) != 0 {
+		t.Fatalf("expect disabled, but: %+v", l.lines)
+	}
+
+	mdb.SetSlowQuery(time.Nanosecond)
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>?", 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.lines) != 1 {
+		t.Fatalf("expect 1 slow query, but: %+v", l.lines)
+	}
+	line := l.lines[0]
+	for _, expect := range []string{"slow query", "QueryElem", "db_slow_test.go", "SELECT COUNT(*) FROM user WHERE id>?", "[0]"} {
+		if !strings.Contains(line, expect) {
+			t.Fatalf("expect '%s' in: %s", expect, line)
+		}
+	}
+}
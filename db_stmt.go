@@ -0,0 +1,205 @@
+package qsql
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+type stmtEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // the count of running operations using the stmt
+	evicted bool // close the stmt when the refs is 0
+}
+
+// The LRU cache of prepared statements keyed by the sql.
+type dbStmtCache struct {
+	mu    sync.Mutex
+	size  int
+	lru   *list.List // *stmtEntry, the front is the recently used
+	items map[string]*list.Element
+}
+
+// Set the max count of the prepared statements to cache, the query helpers, InsertStruct, Exec and Query
+// run with the cached statement of the sql, and it's bound to the tx by tx.Stmt in Commit.
+// The least recently used statement is closed when the cache is full, and all are closed when the db closed.
+// It's disabled when the size is 0, that's the default.
+func (db *DB) SetStmtCacheSize(size int) {
+	db.stmts.resize(size)
+}
+
+func (c *dbStmtCache) enabled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size > 0
+}
+
+func (c *dbStmtCache) resize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+	if c.lru == nil {
+		c.lru = list.New()
+		c.items = map[string]*list.Element{}
+	}
+	for c.lru.Len() > 0 && c.lru.Len() > size {
+		c.evictLocked(c.lru.Back())
+	}
+}
+
+func (c *dbStmtCache) evictLocked(elem *list.Element) {
+	e := c.lru.Remove(elem).(*stmtEntry)
+	delete(c.items, e.query)
+	e.evicted = true
+	if e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+// Return the cached statement of the query, prepare it when it's not cached and the db is not nil,
+// nil when the cache is disabled or failed to prepare.
+// The entry should be released after using.
+func (c *dbStmtCache) acquire(ctx context.Context, db *sql.DB, query string) *stmtEntry {
+	c.mu.Lock()
+	if c.size <= 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	if elem, ok := c.items[query]; ok {
+		c.lru.MoveToFront(elem)
+		e := elem.Value.(*stmtEntry)
+		e.refs++
+		c.mu.Unlock()
+		return e
+	}
+	c.mu.Unlock()
+	if db == nil {
+		return nil
+	}
+
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		// run without the stmt to return the error
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[query]; ok {
+		// prepared by others
+		stmt.Close()
+		c.lru.MoveToFront(elem)
+		e := elem.Value.(*stmtEntry)
+		e.refs++
+		return e
+	}
+	e := &stmtEntry{query: query, stmt: stmt, refs: 1}
+	if c.size <= 0 {
+		// disabled when preparing
+		e.evicted = true
+		return e
+	}
+	c.items[query] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		c.evictLocked(c.lru.Back())
+	}
+	return e
+}
+
+func (c *dbStmtCache) release(e *stmtEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.refs--
+	if e.evicted && e.refs == 0 {
+		e.stmt.Close()
+	}
+}
+
+// Close all the cached statements.
+func (c *dbStmtCache) closeAll() {
+	c.resize(0)
+}
+
+// Return the stmt of tx for the query, it's bound from the stmt of db by tx.Stmt,
+// or prepared on the tx when the stmt of db is nil, and it's closed when the tx done.
+func (tx *Tx) cachedStmt(ctx context.Context, query string, stmt *sql.Stmt) *sql.Stmt {
+	tx.stmtMu.Lock()
+	defer tx.stmtMu.Unlock()
+	if txStmt, ok := tx.stmts[query]; ok {
+		return txStmt
+	}
+	var txStmt *sql.Stmt
+	if stmt != nil {
+		txStmt = tx.Tx.StmtContext(ctx, stmt)
+	} else {
+		var err error
+		txStmt, err = tx.Tx.PrepareContext(ctx, query)
+		if err != nil {
+			// run without the stmt to return the error
+			return nil
+		}
+	}
+	if tx.stmts == nil {
+		tx.stmts = map[string]*sql.Stmt{}
+	}
+	tx.stmts[query] = txStmt
+	return txStmt
+}
+
+// Run the sql with the cached statements of db.
+type stmtConn struct {
+	sqlConn
+	db *DB
+	tx *Tx
+}
+
+// Return the statement of query and the function to release it, nil if it's not available.
+func (c *stmtConn) stmt(ctx context.Context, query string) (*sql.Stmt, func()) {
+	if c.tx == nil {
+		e := c.db.stmts.acquire(ctx, c.db.DB, query)
+		if e == nil {
+			return nil, nil
+		}
+		return e.stmt, func() { c.db.stmts.release(e) }
+	}
+
+	// don't prepare on the db in tx, it may wait for the connection hold by the tx.
+	e := c.db.stmts.acquire(ctx, nil, query)
+	if e == nil {
+		txStmt := c.tx.cachedStmt(ctx, query, nil)
+		return txStmt, func() {}
+	}
+	// the stmt of tx depends on the stmt of db, it's safe to release the stmt of db after bound.
+	txStmt := c.tx.cachedStmt(ctx, query, e.stmt)
+	c.db.stmts.release(e)
+	return txStmt, func() {}
+}
+
+func (c *stmtConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, release := c.stmt(ctx, query)
+	if stmt == nil {
+		return c.sqlConn.ExecContext(ctx, query, args...)
+	}
+	defer release()
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (c *stmtConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, release := c.stmt(ctx, query)
+	if stmt == nil {
+		return c.sqlConn.QueryContext(ctx, query, args...)
+	}
+	defer release()
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (c *stmtConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, release := c.stmt(ctx, query)
+	if stmt == nil {
+		return c.sqlConn.QueryRowContext(ctx, query, args...)
+	}
+	defer release()
+	return stmt.QueryRowContext(ctx, args...)
+}
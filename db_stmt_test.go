@@ -0,0 +1,72 @@
+package qsql
+
+import (
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	mdb.SetStmtCacheSize(2)
+
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	countSql := "SELECT COUNT(*) FROM user WHERE id>?"
+	for i := 0; i < 3; i++ {
+		if err := mdb.QueryElem(&count, countSql, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 1 || mdb.stmts.lru.Len() != 2 {
+		t.Fatalf("expect 2 cached stmts, but: %d", mdb.stmts.lru.Len())
+	}
+	evicted := mdb.stmts.lru.Back().Value.(*stmtEntry)
+
+	names := []string{}
+	if err := mdb.QueryElems(&names, "SELECT username FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || mdb.stmts.lru.Len() != 2 || mdb.stmts.lru.Front().Value.(*stmtEntry).query != "SELECT username FROM user" {
+		t.Fatalf("unexpect cache: %+v", names)
+	}
+	if _, ok := mdb.stmts.items[evicted.query]; ok {
+		t.Fatalf("expect evicted: %s", evicted.query)
+	}
+	if _, err := evicted.stmt.Exec(); err == nil {
+		t.Fatal("expect the evicted stmt closed")
+	}
+
+	// bound to the tx, the only connection is hold by the tx.
+	if err := mdb.Commit(func(tx *Tx) error {
+		for _, name := range []string{"t2", "t3"} {
+			if _, err := tx.Exec("INSERT INTO user (username) VALUES (?)", name); err != nil {
+				return err
+			}
+		}
+		if err := tx.QueryElem(&count, countSql, 0); err != nil {
+			return err
+		}
+		if len(tx.stmts) != 2 {
+			t.Fatalf("expect 2 stmts of tx, but: %d", len(tx.stmts))
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expect 3, but: %d", count)
+	}
+
+	cached := mdb.stmts.lru.Front().Value.(*stmtEntry)
+	if err := mdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if mdb.stmts.lru.Len() != 0 {
+		t.Fatal("expect closed")
+	}
+	if _, err := cached.stmt.Exec(); err == nil {
+		t.Fatal("expect the cached stmt closed")
+	}
+}
@@ -0,0 +1,38 @@
+package qsql
+
+import (
+	"context"
+	"time"
+)
+
+type queryTimeoutCtxKey struct{}
+
+// Set the timeout of the operations with the ctx, it overwrites the default of db, 0 is no timeout.
+// The deadline of ctx is always used when it's set.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutCtxKey{}, timeout)
+}
+
+// Set the default timeout of the operations whose context has no deadline, including Commit,
+// the timeout of Commit is for the whole tx.
+// It's disabled when the timeout is 0, that's the default.
+func (db *DB) SetQueryTimeout(timeout time.Duration) {
+	db.setOptions(func(o *dbOptions) {
+		o.queryTimeout = timeout
+	})
+}
+
+// Return the ctx with the timeout of db when the ctx has no deadline.
+func queryTimeoutCtx(db *DB, ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	timeout, ok := ctx.Value(queryTimeoutCtxKey{}).(time.Duration)
+	if !ok {
+		timeout = db.options().queryTimeout
+	}
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
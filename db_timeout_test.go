@@ -0,0 +1,134 @@
+package qsql
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/gwaylib/errors"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the rows are readable after returned
+	mdb.SetQueryTimeout(time.Minute)
+	rows, err := mdb.Query("SELECT username FROM user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for rows.Next() {
+		name := ""
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	Close(rows)
+	if len(names) != 1 {
+		t.Fatalf("unexpect names: %+v", names)
+	}
+	name := ""
+	if err := mdb.QueryRow("SELECT username FROM user").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+
+	mdb.SetQueryTimeout(time.Nanosecond)
+	count := 0
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user"); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect timeout, but: %v", err)
+	}
+	if _, err := NewPageSql("SELECT COUNT(*) FROM user", "").QueryCount(mdb); !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect timeout of page count, but: %v", err)
+	}
+	if err := mdb.Commit(func(tx *Tx) error {
+		return tx.QueryElem(&count, "SELECT COUNT(*) FROM user")
+	}); !errors.Equal(err, context.DeadlineExceeded) {
+		t.Fatalf("expect timeout of commit, but: %v", err)
+	}
+
+	// overwrite by the call
+	if err := mdb.QueryElemContext(WithQueryTimeout(context.TODO(), 0), &count, "SELECT COUNT(*) FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPageSql("SELECT COUNT(*) FROM user", "").QueryCountContext(WithQueryTimeout(context.TODO(), 0), mdb); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+	if err := mdb.QueryElemContext(ctx, &count, "SELECT COUNT(*) FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mdb.CommitContext(ctx, nil, func(tx *Tx) error {
+		return tx.QueryElem(&count, "SELECT COUNT(*) FROM user")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseIniSeconds(t *testing.T) {
+	for val, expect := range map[string]time.Duration{
+		"30":    30 * time.Second,
+		"500ms": 500 * time.Millisecond,
+		"0":     0,
+	} {
+		d, err := parseIniSeconds(val)
+		if err != nil || d != expect {
+			t.Fatalf("%s: expect %s, but: %s, %v", val, expect, d, err)
+		}
+	}
+	if _, err := parseIniSeconds("30x"); err == nil {
+		t.Fatal("expect error")
+	}
+}
+
+func TestQueryTimeoutRelease(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	var opCtx context.Context
+	mdb.Use(func(ctx context.Context, op *Operation, next Invoker) error {
+		opCtx = ctx
+		return next(ctx, op)
+	})
+	mdb.SetQueryTimeout(time.Minute)
+	rows, err := mdb.Query("SELECT id, username FROM user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opCtx.Err() != nil {
+		t.Fatalf("expect the ctx alive when reading, but: %v", opCtx.Err())
+	}
+	columns, err := rows.ColumnTypes()
+	if err != nil || len(columns) != 2 || columns[1].Name() != "username" {
+		t.Fatalf("unexpect columns: %+v, %v", columns, err)
+	}
+	users := []txTestUser{}
+	if err := scanStructs(rows, &users); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].UserName != "t1" {
+		t.Fatalf("unexpect users: %+v", users)
+	}
+	// the timer of timeout is released when the rows closed
+	if opCtx.Err() != context.Canceled {
+		t.Fatalf("expect the ctx released, but: %v", opCtx.Err())
+	}
+
+	// released by the failed query
+	if _, err := mdb.Query("SELECT * FROM unknow"); err == nil {
+		t.Fatal("expect error")
+	}
+	if opCtx.Err() != context.Canceled {
+		t.Fatalf("expect the ctx released, but: %v", opCtx.Err())
+	}
+}
@@ -0,0 +1,87 @@
+package qsql
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// The span of a qsql operation, adapt it to the tracing system.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	// End the span with the error of operation, the err is nil when success.
+	End(err error)
+}
+
+// The tracer to open a span for every qsql operation, adapt it to the tracing system.
+//
+// The attributes of span:
+// "db.system" -- the driver name.
+// "db.name" -- the name of db, it's the cache key or ini section name.
+// "db.statement" -- the sql of operation, empty for Commit.
+// "db.rows_affected" -- the rows affected of exec, or the rows returned of query.
+type Tracer interface {
+	// Start a span as the child of the span in ctx, and return a new ctx carried the new span.
+	Start(ctx context.Context, spanName string) (context.Context, Span)
+}
+
+var tracer atomic.Value // tracerHolder
+
+type tracerHolder struct {
+	t Tracer
+}
+
+// Set the default tracer for all dbs, it's nil by default.
+func SetTracer(t Tracer) {
+	tracer.Store(tracerHolder{t})
+}
+
+// Set the tracer of db, it overwrites the default of SetTracer.
+func (db *DB) SetTracer(t Tracer) {
+	db.setOptions(func(o *dbOptions) {
+		o.tracer = t
+	})
+}
+
+func tracerOf(db *DB) Tracer {
+	if db != nil {
+		if t := db.options().tracer; t != nil {
+			return t
+		}
+	}
+	h, _ := tracer.Load().(tracerHolder)
+	return h.t
+}
+
+// Start the span of operation,
+// the span is the child of tx span when it's an operation in tx, and the returned ctx is the input one,
+// or the child of span in ctx, and the returned ctx carried the new span.
+func startSpan(db *DB, tx *Tx, ctx context.Context, op *Operation) (context.Context, Span) {
+	t := tracerOf(db)
+	if t == nil {
+		return ctx, nil
+	}
+	var span Span
+	if tx != nil && tx.ctx != nil {
+		_, span = t.Start(tx.ctx, "qsql."+op.Name)
+	} else {
+		ctx, span = t.Start(ctx, "qsql."+op.Name)
+	}
+	span.SetAttribute("db.system", op.Driver)
+	if db != nil {
+		span.SetAttribute("db.name", db.Name())
+	}
+	if len(op.Sql) > 0 {
+		span.SetAttribute("db.statement", op.Sql)
+	}
+	return ctx, span
+}
+
+func endSpan(span Span, op *Operation) {
+	if span == nil {
+		return
+	}
+	if len(op.Sql) > 0 {
+		span.SetAttribute("db.rows_affected", op.RowsAffected)
+	}
+	span.End(op.Err)
+}
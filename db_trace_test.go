@@ -0,0 +1,92 @@
+package qsql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/gwaylib/errors"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name   string
+	parent *testSpan
+	attrs  map[string]interface{}
+	ended  bool
+	err    error
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func TestTracer(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	mdb.setName("trace")
+
+	tracer := &testTracer{}
+	mdb.SetTracer(tracer)
+
+	root := &testSpan{name: "root"}
+	ctx := context.WithValue(context.TODO(), testSpanKey{}, root)
+	if _, err := mdb.InsertStructContext(ctx, &txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mdb.CommitContext(ctx, nil, func(tx *Tx) error {
+		_, err := tx.Exec("INSERT INTO user (username) VALUES (?)", "t2")
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	user := txTestUser{}
+	if err := mdb.QueryStruct(&user, "SELECT * FROM user WHERE username=?", "none"); !errors.ErrNoData.Equal(err) {
+		t.Fatalf("expect no data, but: %v", err)
+	}
+
+	names := []string{}
+	for _, span := range tracer.spans {
+		names = append(names, span.name)
+		if !span.ended {
+			t.Fatalf("span not ended: %s", span.name)
+		}
+	}
+	if strings.Join(names, ",") != "qsql.InsertStruct,qsql.Commit,qsql.Exec,qsql.QueryStruct" {
+		t.Fatalf("unexpect spans: %+v", names)
+	}
+	insert, commit, exec, query := tracer.spans[0], tracer.spans[1], tracer.spans[2], tracer.spans[3]
+	if insert.parent != root || commit.parent != root || query.parent != nil {
+		t.Fatal("unexpect parent of operation")
+	}
+	if exec.parent != commit {
+		t.Fatal("expect the exec of tx is the child of commit")
+	}
+	if insert.attrs["db.system"] != "sqlite" || insert.attrs["db.name"] != "trace" ||
+		!strings.HasPrefix(insert.attrs["db.statement"].(string), "INSERT INTO user") || insert.attrs["db.rows_affected"] != int64(1) {
+		t.Fatalf("unexpect attributes: %+v", insert.attrs)
+	}
+	if _, ok := commit.attrs["db.statement"]; ok {
+		t.Fatalf("unexpect statement of commit: %+v", commit.attrs)
+	}
+	if !errors.ErrNoData.Equal(query.err) {
+		t.Fatalf("expect no data error of query, but: %v", query.err)
+	}
+}
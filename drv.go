@@ -1,6 +1,11 @@
 package qsql
 
-import "github.com/gwaylib/errors"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gwaylib/errors"
+)
 
 const (
 	DRV_NAME_MYSQL     = "mysql"
@@ -9,16 +14,20 @@ const (
 	DRV_NAME_SQLITE3   = "sqlite3"
 	DRV_NAME_SQLSERVER = "sqlserver" // or "mssql"
 
-	_DRV_NAME_OCI8  = "oci8"
-	_DRV_NAME_MSSQL = "mssql"
+	_DRV_NAME_OCI8   = "oci8"
+	_DRV_NAME_MSSQL  = "mssql"
+	_DRV_NAME_SQLITE = "sqlite" // modernc.org/sqlite
 )
 
 func getDrvName(exec Execer, driverName ...string) string {
 	drvName := ""
-	db, ok := exec.(*DB)
-	if ok {
-		drvName = db.DriverName()
-	} else {
+	switch e := exec.(type) {
+	case *DB:
+		drvName = e.DriverName()
+	case *Tx:
+		drvName = e.DriverName()
+	}
+	if len(drvName) == 0 {
 		drvNamesLen := len(driverName)
 		if drvNamesLen > 0 {
 			if drvNamesLen != 1 {
@@ -32,3 +41,49 @@ func getDrvName(exec Execer, driverName ...string) string {
 	}
 	return drvName
 }
+
+// Return the savepoint sql of the driver, the release sql is empty when the driver does not support it.
+func savepointSql(drvName, name string) (save, rollback, release string) {
+	switch drvName {
+	case DRV_NAME_SQLSERVER, _DRV_NAME_MSSQL:
+		return "SAVE TRANSACTION " + name, "ROLLBACK TRANSACTION " + name, ""
+	case DRV_NAME_ORACLE, _DRV_NAME_OCI8:
+		return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, ""
+	default:
+		// mysql, postgres, sqlite3
+		return "SAVEPOINT " + name, "ROLLBACK TO SAVEPOINT " + name, "RELEASE SAVEPOINT " + name
+	}
+}
+
+// Return the placeholder of the driver for the arg index, start from 1.
+func bindVar(drvName string, idx int) string {
+	switch drvName {
+	case DRV_NAME_ORACLE, _DRV_NAME_OCI8:
+		return ":" + strconv.Itoa(idx)
+	case DRV_NAME_POSTGRES:
+		return "$" + strconv.Itoa(idx)
+	case DRV_NAME_SQLSERVER, _DRV_NAME_MSSQL:
+		return "@p" + strconv.Itoa(idx)
+	default:
+		return "?"
+	}
+}
+
+// Replace the '?' placeholders of sql with the placeholders of driver,
+// the '?' in the strings, quoted identifiers, comments and the '?|' operator of postgres are kept.
+func rebind(drvName, querySql string) string {
+	if bindVar(drvName, 1) == "?" {
+		return querySql
+	}
+	buff := strings.Builder{}
+	paramIdx := 1
+	for _, tok := range lexSql(drvName, querySql) {
+		if tok.kind == sqlPlaceholder && tok.text == "?" {
+			buff.WriteString(bindVar(drvName, paramIdx))
+			paramIdx++
+		} else {
+			buff.WriteString(tok.text)
+		}
+	}
+	return buff.String()
+}
@@ -0,0 +1,311 @@
+package qsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Return the error code of the driver error without importing the driver,
+// the code is formatted to string, like "1213" of mysql, "40001" of postgres.
+func drvErrCode(err error) string {
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		if code := reflectErrCode(e); len(code) > 0 {
+			return code
+		}
+	}
+	return ""
+}
+
+func reflectErrCode(err error) string {
+	switch e := err.(type) {
+	case interface{ SQLState() string }:
+		// github.com/jackc/pgx
+		return e.SQLState()
+	case interface{ Code() int }:
+		// modernc.org/sqlite
+		return fmt.Sprint(e.Code())
+	}
+
+	v := reflect.ValueOf(err)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	// github.com/go-sql-driver/mysql, github.com/denisenkom/go-mssqldb
+	// github.com/lib/pq, github.com/mattn/go-sqlite3, github.com/godror/godror
+	for _, name := range []string{"Number", "ExtendedCode", "Code"} {
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if f.Int() != 0 {
+				return fmt.Sprint(f.Int())
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if f.Uint() != 0 {
+				return fmt.Sprint(f.Uint())
+			}
+		case reflect.String:
+			if f.Len() > 0 {
+				return f.String()
+			}
+		}
+	}
+	return ""
+}
+
+// Checking the error message contains one of the keywords, the keywords should be lower case.
+func errMsgContains(err error, keywords ...string) bool {
+	msg := strings.ToLower(err.Error())
+	for _, k := range keywords {
+		if strings.Contains(msg, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// The package path of the drivers and their errors.
+var drvErrPkgs = map[string]string{
+	"github.com/go-sql-driver/mysql":     DRV_NAME_MYSQL,
+	"github.com/lib/pq":                  DRV_NAME_POSTGRES,
+	"github.com/jackc/pgx/v4/pgconn":     DRV_NAME_POSTGRES,
+	"github.com/jackc/pgx/v5/pgconn":     DRV_NAME_POSTGRES,
+	"github.com/jackc/pgconn":            DRV_NAME_POSTGRES,
+	"github.com/jackc/pgx/v4/stdlib":     DRV_NAME_POSTGRES,
+	"github.com/jackc/pgx/v5/stdlib":     DRV_NAME_POSTGRES,
+	"github.com/denisenkom/go-mssqldb":   DRV_NAME_SQLSERVER,
+	"github.com/microsoft/go-mssqldb":    DRV_NAME_SQLSERVER,
+	"github.com/godror/godror":           DRV_NAME_ORACLE,
+	"github.com/mattn/go-oci8":           DRV_NAME_ORACLE,
+	"github.com/sijms/go-ora/v2/network": DRV_NAME_ORACLE,
+	"github.com/mattn/go-sqlite3":        DRV_NAME_SQLITE3,
+	"modernc.org/sqlite":                 DRV_NAME_SQLITE3,
+	"modernc.org/sqlite/lib":             DRV_NAME_SQLITE3,
+}
+
+// Return the driver name of the error, it's from the QueryError or the package of driver error,
+// empty when unknow.
+func errDrvName(err error) string {
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		if qErr, ok := e.(*QueryError); ok && len(qErr.Driver) > 0 {
+			return stdDrvName(qErr.Driver)
+		}
+		t := reflect.TypeOf(e)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if drvName, ok := drvErrPkgs[t.PkgPath()]; ok {
+			return drvName
+		}
+	}
+	return ""
+}
+
+// Return the driver name of the sql.DB by the package of driver, empty when unknow.
+func sqlDBDrvName(db *sql.DB) string {
+	t := reflect.TypeOf(db.Driver())
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return drvErrPkgs[t.PkgPath()]
+}
+
+// Return the standard name of the alias driver name.
+func stdDrvName(drvName string) string {
+	switch drvName {
+	case _DRV_NAME_OCI8:
+		return DRV_NAME_ORACLE
+	case _DRV_NAME_MSSQL:
+		return DRV_NAME_SQLSERVER
+	case _DRV_NAME_SQLITE:
+		return DRV_NAME_SQLITE3
+	}
+	return drvName
+}
+
+// The error codes and messages of a kind of error for a driver.
+type drvErrRule struct {
+	codes []string // the sqlite codes are matched with the extended code or the primary code
+	msgs  []string // lower case
+}
+
+type drvErrRules map[string]drvErrRule // key by the standard driver name
+
+// Checking the error matches the rule of the driver, the driver is detected by the error when the drvName is empty,
+// and only the messages of all drivers are checked when it's unknow.
+func (rules drvErrRules) match(drvName string, err error) bool {
+	if err == nil {
+		return false
+	}
+	drvName = stdDrvName(drvName)
+	if len(drvName) == 0 {
+		drvName = errDrvName(err)
+	}
+	rule, ok := rules[drvName]
+	if !ok {
+		for _, r := range rules {
+			if errMsgContains(err, r.msgs...) {
+				return true
+			}
+		}
+		return false
+	}
+	if code := drvErrCode(err); len(code) > 0 {
+		primary := ""
+		if drvName == DRV_NAME_SQLITE3 {
+			primary = strconv.FormatInt(sqlitePrimaryCode(code), 10)
+		}
+		for _, c := range rule.codes {
+			if c == code || c == primary {
+				return true
+			}
+		}
+	}
+	return errMsgContains(err, rule.msgs...)
+}
+
+var (
+	duplicateKeyRules = drvErrRules{
+		DRV_NAME_MYSQL:     {codes: []string{"1062", "1586"}, msgs: []string{"duplicate entry"}},
+		DRV_NAME_POSTGRES:  {codes: []string{"23505"}, msgs: []string{"violates unique constraint"}},
+		DRV_NAME_SQLSERVER: {codes: []string{"2627", "2601"}, msgs: []string{"violation of unique key constraint", "violation of primary key constraint", "cannot insert duplicate key"}},
+		DRV_NAME_ORACLE:    {codes: []string{"1"}, msgs: []string{"ora-00001"}},
+		// SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+		DRV_NAME_SQLITE3: {codes: []string{"2067", "1555"}, msgs: []string{"unique constraint failed"}},
+	}
+	foreignKeyRules = drvErrRules{
+		DRV_NAME_MYSQL:    {codes: []string{"1451", "1452", "1216", "1217"}, msgs: []string{"a foreign key constraint fails"}},
+		DRV_NAME_POSTGRES: {codes: []string{"23503"}, msgs: []string{"violates foreign key constraint"}},
+		// 547 is used by the check constraint too
+		DRV_NAME_SQLSERVER: {msgs: []string{"conflicted with the foreign key constraint"}},
+		DRV_NAME_ORACLE:    {codes: []string{"2291", "2292"}, msgs: []string{"ora-02291", "ora-02292"}},
+		// SQLITE_CONSTRAINT_FOREIGNKEY
+		DRV_NAME_SQLITE3: {codes: []string{"787"}, msgs: []string{"foreign key constraint failed"}},
+	}
+	checkRules = drvErrRules{
+		// Check constraint 'xxx' is violated.
+		DRV_NAME_MYSQL:     {codes: []string{"3819"}, msgs: []string{"' is violated."}},
+		DRV_NAME_POSTGRES:  {codes: []string{"23514"}, msgs: []string{"violates check constraint"}},
+		DRV_NAME_SQLSERVER: {msgs: []string{"conflicted with the check constraint"}},
+		DRV_NAME_ORACLE:    {codes: []string{"2290"}, msgs: []string{"ora-02290"}},
+		// SQLITE_CONSTRAINT_CHECK
+		DRV_NAME_SQLITE3: {codes: []string{"275"}, msgs: []string{"check constraint failed"}},
+	}
+	deadlockRules = drvErrRules{
+		DRV_NAME_MYSQL:     {codes: []string{"1213"}, msgs: []string{"error 1213", "deadlock found"}},
+		DRV_NAME_POSTGRES:  {codes: []string{"40P01"}, msgs: []string{"deadlock detected"}},
+		DRV_NAME_SQLSERVER: {codes: []string{"1205"}, msgs: []string{"deadlocked on lock"}},
+		DRV_NAME_ORACLE:    {codes: []string{"60"}, msgs: []string{"ora-00060"}},
+	}
+	serializationRules = drvErrRules{
+		DRV_NAME_POSTGRES: {codes: []string{"40001"}, msgs: []string{"could not serialize access"}},
+		DRV_NAME_ORACLE:   {codes: []string{"8177"}, msgs: []string{"ora-08177"}},
+	}
+	lockTimeoutRules = drvErrRules{
+		DRV_NAME_MYSQL:     {codes: []string{"1205"}, msgs: []string{"lock wait timeout exceeded"}},
+		DRV_NAME_POSTGRES:  {codes: []string{"55P03"}, msgs: []string{"could not obtain lock", "canceling statement due to lock timeout"}},
+		DRV_NAME_SQLSERVER: {codes: []string{"1222"}, msgs: []string{"lock request time out period exceeded"}},
+		DRV_NAME_ORACLE:    {codes: []string{"30006", "54"}, msgs: []string{"ora-30006", "ora-00054"}},
+		// SQLITE_BUSY, SQLITE_LOCKED and their extended codes
+		DRV_NAME_SQLITE3: {codes: []string{"5", "6"}, msgs: []string{"database is locked", "database table is locked"}},
+	}
+	connectionRules = drvErrRules{
+		DRV_NAME_MYSQL: {
+			codes: []string{"1040", "1053", "2002", "2003", "2006", "2013"},
+			msgs:  []string{"invalid connection", "server has gone away", "lost connection"},
+		},
+		DRV_NAME_POSTGRES: {
+			codes: []string{"08000", "08001", "08003", "08004", "08006", "57P01", "57P02", "57P03"},
+			msgs:  []string{"connection refused", "connection reset", "terminating connection"},
+		},
+		DRV_NAME_SQLSERVER: {msgs: []string{"connection refused", "connection reset", "unable to open tcp connection"}},
+		DRV_NAME_ORACLE: {
+			codes: []string{"3113", "3114", "3135", "12170", "12514", "12541"},
+			msgs:  []string{"ora-03113", "ora-03114", "ora-03135", "ora-12170", "ora-12514", "ora-12541"},
+		},
+		// SQLITE_CANTOPEN
+		DRV_NAME_SQLITE3: {codes: []string{"14"}, msgs: []string{"unable to open database"}},
+	}
+)
+
+// Checking the error is a violation of unique index or primary key.
+func IsDuplicateKey(err error) bool {
+	return duplicateKeyRules.match("", err)
+}
+
+// Checking the error is a violation of foreign key.
+func IsForeignKeyViolation(err error) bool {
+	return foreignKeyRules.match("", err)
+}
+
+// Checking the error is a violation of check constraint.
+func IsCheckViolation(err error) bool {
+	return checkRules.match("", err)
+}
+
+// Checking the error is a deadlock.
+func IsDeadlock(err error) bool {
+	return deadlockRules.match("", err)
+}
+
+// Checking the error is a timeout of waiting lock, the sqlite busy is counted in.
+func IsLockTimeout(err error) bool {
+	return lockTimeoutRules.match("", err)
+}
+
+// Checking the error is a broken or refused connection,
+// the timeout is not counted in except dialing, like the context.DeadlineExceeded of a slow query.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if stderrors.Is(err, driver.ErrBadConn) || stderrors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		var opErr *net.OpError
+		if !netErr.Timeout() || (stderrors.As(err, &opErr) && opErr.Op == "dial") {
+			return true
+		}
+	}
+	return connectionRules.match("", err)
+}
+
+// Checking the error is a deadlock or serialization failure which can be resolved by rerun the tx.
+func isRetryableError(drvName string, err error) bool {
+	if err == nil || stderrors.Is(err, driver.ErrBadConn) {
+		return false
+	}
+	if len(drvName) == 0 {
+		drvName = errDrvName(err)
+	}
+	if deadlockRules.match(drvName, err) || serializationRules.match(drvName, err) {
+		return true
+	}
+	// the lock of sqlite is released soon
+	return stdDrvName(drvName) == DRV_NAME_SQLITE3 && lockTimeoutRules.match(drvName, err)
+}
+
+// The low byte of sqlite extended result code is the primary result code.
+func sqlitePrimaryCode(code string) int64 {
+	c, err := strconv.ParseInt(code, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return c & 0xff
+}
@@ -0,0 +1,199 @@
+package qsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gwaylib/errors"
+	"github.com/lib/pq"
+)
+
+type mssqlTestError struct {
+	Number  int32
+	Message string
+}
+
+func (e *mssqlTestError) Error() string {
+	return "mssql: " + e.Message
+}
+
+type oraTestError struct {
+	Code    int
+	Message string
+}
+
+func (e *oraTestError) Error() string {
+	return e.Message
+}
+
+func TestDrvErrorRecorded(t *testing.T) {
+	cases := []struct {
+		drvName string
+		err     error
+		expect  string
+	}{
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'username'"}, "IsDuplicateKey"},
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails"}, "IsForeignKeyViolation"},
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 3819, Message: "Check constraint 'age_chk' is violated."}, "IsCheckViolation"},
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, "IsDeadlock"},
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded; try restarting transaction"}, "IsLockTimeout"},
+		{DRV_NAME_MYSQL, &mysql.MySQLError{Number: 2006, Message: "MySQL server has gone away"}, "IsConnectionError"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "user_pkey"`}, "IsDuplicateKey"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "23503", Message: "insert or update on table violates foreign key constraint"}, "IsForeignKeyViolation"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "23514", Message: "new row violates check constraint"}, "IsCheckViolation"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "40P01", Message: "deadlock detected"}, "IsDeadlock"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "55P03", Message: "could not obtain lock on row"}, "IsLockTimeout"},
+		{DRV_NAME_POSTGRES, &pq.Error{Code: "57P01", Message: "terminating connection due to administrator command"}, "IsConnectionError"},
+		{_DRV_NAME_MSSQL, &mssqlTestError{Number: 2627, Message: "Violation of PRIMARY KEY constraint 'PK_user'."}, "IsDuplicateKey"},
+		{_DRV_NAME_MSSQL, &mssqlTestError{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint"}, "IsForeignKeyViolation"},
+		{_DRV_NAME_MSSQL, &mssqlTestError{Number: 547, Message: "The INSERT statement conflicted with the CHECK constraint"}, "IsCheckViolation"},
+		{_DRV_NAME_MSSQL, &mssqlTestError{Number: 1205, Message: "Transaction was deadlocked on lock resources"}, "IsDeadlock"},
+		{_DRV_NAME_MSSQL, &mssqlTestError{Number: 1222, Message: "Lock request time out period exceeded."}, "IsLockTimeout"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 1, Message: "ORA-00001: unique constraint (U.PK) violated"}, "IsDuplicateKey"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 2291, Message: "ORA-02291: integrity constraint violated - parent key not found"}, "IsForeignKeyViolation"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 2290, Message: "ORA-02290: check constraint violated"}, "IsCheckViolation"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 60, Message: "ORA-00060: deadlock detected while waiting for resource"}, "IsDeadlock"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 54, Message: "ORA-00054: resource busy and acquire with NOWAIT specified"}, "IsLockTimeout"},
+		{DRV_NAME_ORACLE, &oraTestError{Code: 3113, Message: "ORA-03113: end-of-file on communication channel"}, "IsConnectionError"},
+	}
+	checks := map[string]func(error) bool{
+		"IsDuplicateKey":        IsDuplicateKey,
+		"IsForeignKeyViolation": IsForeignKeyViolation,
+		"IsCheckViolation":      IsCheckViolation,
+		"IsDeadlock":            IsDeadlock,
+		"IsLockTimeout":         IsLockTimeout,
+		"IsConnectionError":     IsConnectionError,
+	}
+	for _, c := range cases {
+		err := &QueryError{Driver: c.drvName, Err: c.err}
+		for name, fn := range checks {
+			expect := name == c.expect
+			if fn(err) != expect {
+				t.Fatalf("%s %s: expect %t for %v", c.drvName, name, expect, c.err)
+			}
+		}
+	}
+
+	// the mysql 1205 is a lock timeout but the mssql 1205 is a deadlock
+	if IsDeadlock(&QueryError{Driver: DRV_NAME_MYSQL, Err: &mysql.MySQLError{Number: 1205}}) {
+		t.Fatal("expect mysql 1205 is not a deadlock")
+	}
+	// the driver is unknow after wrapped, checking by message.
+	if !IsDuplicateKey(errors.As(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a'"})) {
+		t.Fatal("expect duplicate key by message")
+	}
+	if !IsConnectionError(driver.ErrBadConn) || !IsConnectionError(fmt.Errorf("dial: %w", &net.OpError{Op: "dial", Err: fmt.Errorf("refused")})) {
+		t.Fatal("expect connection error")
+	}
+	// the timeout is not a broken connection except dialing
+	if IsConnectionError(context.DeadlineExceeded) || IsConnectionError(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}) {
+		t.Fatal("expect timeout is not connection error")
+	}
+	if !IsConnectionError(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}) {
+		t.Fatal("expect dial timeout is connection error")
+	}
+	if IsDuplicateKey(nil) || IsConnectionError(nil) {
+		t.Fatal("expect false for nil")
+	}
+}
+
+func TestDrvErrorPkg(t *testing.T) {
+	// the driver is detected by the package of error without the QueryError
+	if name := errDrvName(&mysql.MySQLError{Number: 1062}); name != DRV_NAME_MYSQL {
+		t.Fatalf("expect mysql, but: %s", name)
+	}
+	if name := errDrvName(fmt.Errorf("commit: %w", &pq.Error{Code: "40P01"})); name != DRV_NAME_POSTGRES {
+		t.Fatalf("expect postgres, but: %s", name)
+	}
+	if !IsDuplicateKey(&mysql.MySQLError{Number: 1062}) || !IsDeadlock(&pq.Error{Code: "40P01"}) {
+		t.Fatal("expect matched by the code of driver")
+	}
+	// the mysql 1205 is a lock timeout but the mssql 1205 is a deadlock
+	if IsDeadlock(&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}) || !IsLockTimeout(&mysql.MySQLError{Number: 1205}) {
+		t.Fatal("expect mysql 1205 is a lock timeout")
+	}
+
+	// the other errors of check constraint are not violation
+	ddlErr := &mysql.MySQLError{Number: 3820, Message: "Check constraint 'age_chk' refers to non-existing column 'age'."}
+	if IsCheckViolation(ddlErr) || IsCheckViolation(errors.As(ddlErr)) {
+		t.Fatalf("expect not check violation: %v", ddlErr)
+	}
+	if !IsCheckViolation(errors.As(&mysql.MySQLError{Number: 3819, Message: "Check constraint 'age_chk' is violated."})) {
+		t.Fatal("expect check violation by message")
+	}
+}
+
+func TestDrvErrorSqlite(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	if _, err := mdb.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mdb.Exec(`CREATE TABLE user_log (
+		"id" INTEGER PRIMARY KEY NOT NULL,
+		"user_id" INTEGER NOT NULL REFERENCES user(id),
+		"times" INTEGER NOT NULL CHECK (times > 0)
+	)`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := mdb.InsertStruct(&txTestUser{UserName: "t1"}, "user")
+	if !IsDuplicateKey(err) || IsForeignKeyViolation(err) || IsCheckViolation(err) {
+		t.Fatalf("expect duplicate key, but: %v", err)
+	}
+	_, err = mdb.Exec("INSERT INTO user_log (user_id, times) VALUES (?, ?)", 100, 1)
+	if !IsForeignKeyViolation(err) || IsDuplicateKey(err) || IsCheckViolation(err) {
+		t.Fatalf("expect foreign key violation, but: %v", err)
+	}
+	_, err = mdb.Exec("INSERT INTO user_log (user_id, times) VALUES (?, ?)", 1, 0)
+	if !IsCheckViolation(err) || IsDuplicateKey(err) || IsForeignKeyViolation(err) {
+		t.Fatalf("expect check violation, but: %v", err)
+	}
+
+	// lock by another connection
+	dsn := "file:" + filepath.Join(t.TempDir(), "lock.db") + "?_pragma=busy_timeout(0)"
+	db1, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db1)
+	db2, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db2)
+	if _, err := db1.Exec("CREATE TABLE t (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db1.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Rollback(tx)
+	if _, err := tx.Exec("INSERT INTO t VALUES (1)"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db2.Exec("INSERT INTO t VALUES (2)")
+	if !IsLockTimeout(err) || IsDeadlock(err) || !isRetryableError(db2.DriverName(), err) {
+		t.Fatalf("expect lock timeout, but: %v", err)
+	}
+
+	// can not open the database
+	db3, err := Open("sqlite", filepath.Join(t.TempDir(), "none", "none.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db3)
+	if _, err := db3.Exec("CREATE TABLE t (id INTEGER)"); !IsConnectionError(err) {
+		t.Fatalf("expect connection error, but: %v", err)
+	}
+}
@@ -2,6 +2,12 @@ module github.com/gwaylib/qsql/example
 
 go 1.25.0
 
+require (
+	github.com/gwaylib/errors v0.0.4
+	github.com/gwaylib/qsql v0.0.0-00010101000000-000000000000
+	modernc.org/sqlite v1.50.1
+)
+
 require (
 	github.com/chzyer/logex v1.2.1 // indirect
 	github.com/chzyer/readline v1.5.1 // indirect
@@ -15,9 +21,7 @@ require (
 	github.com/google/pprof v0.0.0-20250317173921-a4b03ec1a45e // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gwaylib/beanmsq v0.0.0-20250413015903-34e67425271a // indirect
-	github.com/gwaylib/errors v0.0.4 // indirect
 	github.com/gwaylib/log v0.0.6 // indirect
-	github.com/gwaylib/qsql v0.0.0-00010101000000-000000000000 // indirect
 	github.com/gwaylib/redis v0.0.0-20231110105356-a4910e0c1eaf // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/ianlancetaylor/demangle v0.0.0-20240312041847-bd984b5ce465 // indirect
@@ -77,7 +81,6 @@ require (
 	modernc.org/opt v0.2.0 // indirect
 	modernc.org/scannertest v1.0.2 // indirect
 	modernc.org/sortutil v1.2.1 // indirect
-	modernc.org/sqlite v1.50.1 // indirect
 	modernc.org/strutil v1.2.1 // indirect
 	modernc.org/token v1.1.0 // indirect
 )
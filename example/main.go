@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"fmt"
 	"math/rand"
 	"time"
@@ -153,13 +152,13 @@ func main() {
 	fmt.Printf("PageMap data: %+v\n", mData)
 
 	// executer for tx
-	if err := mdb.Commit(func(tx *sql.Tx) error {
+	if err := mdb.Commit(func(tx *qsql.Tx) error {
 		txUsers := []TestingUser{
 			{UserName: "t3", Passwd: "t3"},
 			{UserName: "t4", Passwd: "t4"},
 		}
 		for _, u := range txUsers {
-			if _, err := qsql.InsertStruct(mdb.DriverName(), tx, &u, "user"); err != nil {
+			if _, err := tx.InsertStruct(&u, "user"); err != nil {
 				return errors.As(err)
 			}
 		}
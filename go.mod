@@ -1,9 +1,25 @@
 module github.com/gwaylib/qsql
 
-go 1.12
+go 1.21
 
 require (
 	github.com/go-ini/ini v1.48.0
+	github.com/go-sql-driver/mysql v1.4.0
 	github.com/gwaylib/errors v0.0.4
 	github.com/jmoiron/sqlx v1.2.0
+	github.com/lib/pq v1.0.0
+	modernc.org/sqlite v1.34.5
+)
+
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/sys v0.22.0 // indirect
+	gopkg.in/ini.v1 v1.67.3 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
 )
@@ -1,15 +1,107 @@
 package qsql
 
 import (
-	slog "log"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 )
 
+// The leveled logger of qsql, the kv is the pairs of key and value like the log/slog.
+//
+// The common keys:
+// "section" -- the name of db, see DB.Name.
+// "driver" -- the driver name of db.
+// "op" -- the operation name.
+// "sql" -- the sql of operation.
+// "args" -- the args of sql.
+// "duration" -- the duration of operation.
+// "err" -- the error.
+type Logger interface {
+	Debug(ctx context.Context, msg string, kv ...interface{})
+	Info(ctx context.Context, msg string, kv ...interface{})
+	Warn(ctx context.Context, msg string, kv ...interface{})
+	Error(ctx context.Context, msg string, kv ...interface{})
+}
+
+// Deprecated: using Logger instead.
 type Log interface {
 	Println(msg ...interface{})
 }
 
-var log = Log(slog.Default())
+var log = Logger(NewSlogLogger(nil))
 
-func SetLog(l Log) {
+// Set the logger of qsql, using the slog.Default() when it's nil.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = NewSlogLogger(nil)
+	}
 	log = l
 }
+
+// Set a Println logger like the log.Logger, the level and the kv are printed after the msg like "WARN msg key=value".
+//
+// Deprecated: using SetLogger instead.
+func SetLog(l Log) {
+	if l == nil {
+		SetLogger(nil)
+		return
+	}
+	SetLogger(&printLogger{l})
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// Adapt a slog.Logger to Logger, using the slog.Default() when it's nil.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) logger() *slog.Logger {
+	if s.l == nil {
+		return slog.Default()
+	}
+	return s.l
+}
+func (s *slogLogger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	s.logger().DebugContext(ctx, msg, kv...)
+}
+func (s *slogLogger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	s.logger().InfoContext(ctx, msg, kv...)
+}
+func (s *slogLogger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	s.logger().WarnContext(ctx, msg, kv...)
+}
+func (s *slogLogger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	s.logger().ErrorContext(ctx, msg, kv...)
+}
+
+type printLogger struct {
+	l Log
+}
+
+func (p *printLogger) print(level, msg string, kv []interface{}) {
+	fields := []string{level, msg}
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			fields = append(fields, fmt.Sprintf("%v=%v", kv[i], kv[i+1]))
+		} else {
+			fields = append(fields, fmt.Sprint(kv[i]))
+		}
+	}
+	p.l.Println(strings.Join(fields, " "))
+}
+func (p *printLogger) Debug(ctx context.Context, msg string, kv ...interface{}) {
+	p.print("DEBUG", msg, kv)
+}
+func (p *printLogger) Info(ctx context.Context, msg string, kv ...interface{}) {
+	p.print("INFO", msg, kv)
+}
+func (p *printLogger) Warn(ctx context.Context, msg string, kv ...interface{}) {
+	p.print("WARN", msg, kv)
+}
+func (p *printLogger) Error(ctx context.Context, msg string, kv ...interface{}) {
+	p.print("ERROR", msg, kv)
+}
@@ -0,0 +1,47 @@
+package qsql
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSlogLogger(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	mdb.setName("log")
+
+	buf := &bytes.Buffer{}
+	oriLog := log
+	SetLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	defer SetLogger(oriLog)
+
+	mdb.SetSlowQuery(time.Nanosecond)
+	count := 0
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>?", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	expect := map[string]interface{}{
+		"level":   "WARN",
+		"msg":     "slow query",
+		"section": "log",
+		"driver":  "sqlite",
+		"op":      "QueryElem",
+		"sql":     "SELECT COUNT(*) FROM user WHERE id>?",
+	}
+	for key, val := range expect {
+		if record[key] != val {
+			t.Fatalf("expect %s=%v, but: %s", key, val, buf.String())
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Fatalf("expect duration, but: %s", buf.String())
+	}
+}
@@ -117,12 +117,12 @@ func scanStructs(rows *sql.Rows, obj interface{}) error {
 func queryStruct(db Queryer, ctx context.Context, obj interface{}, querySql string, args ...interface{}) error {
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return errors.As(err, args)
+		return err
 	}
 	defer Close(rows)
 
 	if err := scanStruct(rows, obj); err != nil {
-		return errors.As(err, args)
+		return err
 	}
 	return nil
 }
@@ -130,12 +130,12 @@ func queryStruct(db Queryer, ctx context.Context, obj interface{}, querySql stri
 func queryStructs(db Queryer, ctx context.Context, obj interface{}, querySql string, args ...interface{}) error {
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return errors.As(err, args)
+		return err
 	}
 	defer Close(rows)
 
 	if err := scanStructs(rows, obj); err != nil {
-		return errors.As(err, args)
+		return err
 	}
 
 	return nil
@@ -144,9 +144,9 @@ func queryStructs(db Queryer, ctx context.Context, obj interface{}, querySql str
 func queryElem(db Queryer, ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
 	if err := db.QueryRowContext(ctx, querySql, args...).Scan(result); err != nil {
 		if sql.ErrNoRows == err {
-			return errors.ErrNoData.As(args)
+			return errors.ErrNoData
 		}
-		return errors.As(err, querySql, args)
+		return err
 	}
 	return nil
 }
@@ -167,7 +167,7 @@ func queryElems(db Queryer, ctx context.Context, arr interface{}, querySql strin
 
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return errors.As(err, querySql, args)
+		return err
 	}
 	defer Close(rows)
 
@@ -177,7 +177,7 @@ func queryElems(db Queryer, ctx context.Context, arr interface{}, querySql strin
 	for rows.Next() {
 		vp = reflect.New(base)
 		if err := rows.Scan(vp.Interface()); err != nil {
-			return errors.As(err)
+			return err
 		}
 		if isPtr {
 			direct.Set(reflect.Append(direct, vp))
@@ -196,19 +196,19 @@ func queryPageArr(db Queryer, ctx context.Context, querySql string, args ...inte
 	result = [][]interface{}{}
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return titles, result, errors.As(err, args)
+		return titles, result, err
 	}
 	defer Close(rows)
 
 	titles, err = rows.Columns()
 	if err != nil {
-		return titles, result, errors.As(err, args)
+		return titles, result, err
 	}
 
 	for rows.Next() {
 		r := makeDBDataArr(len(titles))
 		if err := rows.Scan(r...); err != nil {
-			return titles, result, errors.As(err, args)
+			return titles, result, err
 		}
 		result = append(result, r)
 	}
@@ -224,19 +224,19 @@ func queryDBDataArr(db Queryer, ctx context.Context, querySql string, args ...in
 	result = [][]*DBData{}
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return titles, result, errors.As(err, args)
+		return titles, result, err
 	}
 	defer Close(rows)
 
 	titles, err = rows.Columns()
 	if err != nil {
-		return titles, result, errors.As(err, args)
+		return titles, result, err
 	}
 
 	for rows.Next() {
 		r := makeDBDataArr(len(titles))
 		if err := rows.Scan(r...); err != nil {
-			return titles, result, errors.As(err, args)
+			return titles, result, err
 		}
 		result = append(result, coverDBDataArr(r))
 		r = nil // TODO: free memory
@@ -251,20 +251,20 @@ func queryDBDataArr(db Queryer, ctx context.Context, querySql string, args ...in
 func queryPageMap(db Queryer, ctx context.Context, querySql string, args ...interface{}) ([]string, []map[string]interface{}, error) {
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return nil, []map[string]interface{}{}, errors.As(err, args)
+		return nil, []map[string]interface{}{}, err
 	}
 	defer Close(rows)
 
 	titles, err := rows.Columns()
 	if err != nil {
-		return titles, []map[string]interface{}{}, errors.As(err, args)
+		return titles, []map[string]interface{}{}, err
 	}
 
 	result := []map[string]interface{}{}
 	for rows.Next() {
 		r := makeDBDataArr(len(titles))
 		if err := rows.Scan(r...); err != nil {
-			return titles, []map[string]interface{}{}, errors.As(err, args)
+			return titles, []map[string]interface{}{}, err
 		}
 		mData := map[string]interface{}{}
 		for i, title := range titles {
@@ -281,20 +281,20 @@ func queryPageMap(db Queryer, ctx context.Context, querySql string, args ...inte
 func queryDBDataMap(db Queryer, ctx context.Context, querySql string, args ...interface{}) ([]string, []map[string]*DBData, error) {
 	rows, err := db.QueryContext(ctx, querySql, args...)
 	if err != nil {
-		return nil, []map[string]*DBData{}, errors.As(err, args)
+		return nil, []map[string]*DBData{}, err
 	}
 	defer Close(rows)
 
 	titles, err := rows.Columns()
 	if err != nil {
-		return titles, []map[string]*DBData{}, errors.As(err, args)
+		return titles, []map[string]*DBData{}, err
 	}
 
 	result := []map[string]*DBData{}
 	for rows.Next() {
 		r := makeDBDataArr(len(titles))
 		if err := rows.Scan(r...); err != nil {
-			return titles, []map[string]*DBData{}, errors.As(err, args)
+			return titles, []map[string]*DBData{}, err
 		}
 		mData := map[string]*DBData{}
 		for i, title := range titles {
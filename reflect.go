@@ -71,7 +71,7 @@ func _travelSelectField(f *reflectx.FieldInfo, v *reflect.Value, selectNames *[]
 }
 
 // return is it a auto_increment field
-func _travelStructField(f *reflectx.FieldInfo, v *reflect.Value, drvName *string, fieldIdx *int, selectNames *[]string, stmtParams *[]string, scanVals *[]interface{}) *reflect.Value {
+func _travelStructField(f *reflectx.FieldInfo, v *reflect.Value, drvName *string, fieldIdx *int, selectNames *[]string, stmtParams *[]string, scanVals *[]interface{}, redacts *[]int) *reflect.Value {
 	*fieldIdx += 1
 	switch v.Kind() {
 	case reflect.Invalid:
@@ -104,7 +104,7 @@ func _travelStructField(f *reflectx.FieldInfo, v *reflect.Value, drvName *string
 				fieldVal := reflect.Indirect(*v).Field(i)
 				autoFiled := _travelStructField(
 					child, &fieldVal, drvName,
-					fieldIdx, selectNames, stmtParams, scanVals,
+					fieldIdx, selectNames, stmtParams, scanVals, redacts,
 				)
 				if autoFiled != nil {
 					autoIncrement = autoFiled
@@ -158,6 +158,9 @@ func _travelStructField(f *reflectx.FieldInfo, v *reflect.Value, drvName *string
 		*selectNames = append(*selectNames, "\""+f.Name+"\"")
 		*stmtParams = append(*stmtParams, "?")
 	}
+	if _, ok := f.Options["redact"]; ok {
+		*redacts = append(*redacts, len(*scanVals))
+	}
 	*scanVals = append(*scanVals, v.Interface())
 
 	// recursive end by nil
@@ -168,6 +171,8 @@ type reflectInsertField struct {
 	Names  []string
 	Stmts  []string
 	Values []interface{}
+	// the index of values with the 'redact' tag option
+	Redacts []int
 
 	AutoIncrement *reflect.Value
 }
@@ -228,6 +233,7 @@ func reflectInsertStruct(i interface{}, drvName string) (*reflectInsertField, er
 	outputSelectNames := []string{}
 	outputStmtParams := []string{}
 	outputFieldVals := []interface{}{}
+	outputRedacts := []int{}
 	var autoIncrement *reflect.Value
 
 	childrenLen := len(tm.Tree.Children)
@@ -243,7 +249,7 @@ func reflectInsertStruct(i interface{}, drvName string) (*reflectInsertField, er
 		autoField := _travelStructField(
 			field, &fieldVal, &drvName,
 			&fieldIdx,
-			&outputSelectNames, &outputStmtParams, &outputFieldVals,
+			&outputSelectNames, &outputStmtParams, &outputFieldVals, &outputRedacts,
 		)
 		if autoField != nil {
 			autoIncrement = autoField
@@ -257,6 +263,7 @@ func reflectInsertStruct(i interface{}, drvName string) (*reflectInsertField, er
 		Names:         outputSelectNames,
 		Stmts:         outputStmtParams,
 		Values:        outputFieldVals,
+		Redacts:       outputRedacts,
 		AutoIncrement: autoIncrement,
 	}, nil
 }
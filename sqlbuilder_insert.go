@@ -16,18 +16,20 @@ const (
 
 // field flag like: `db:"name"`
 // more: github.com/jmoiron/sqlx
-func insertStruct(exec Execer, ctx context.Context, obj interface{}, tbName string, driverName ...string) (sql.Result, error) {
-	drvName := getDrvName(exec, driverName...)
-
+func reflectInsertSql(obj interface{}, tbName, drvName string) (string, *reflectInsertField, error) {
 	fields, err := reflectInsertStruct(obj, drvName)
 	if err != nil {
-		return nil, errors.As(err)
+		return "", nil, errors.As(err)
 	}
 	execSql := fmt.Sprintf(addObjSql, tbName, strings.Join(fields.Names, ", "), strings.Join(fields.Stmts, ", "))
+	return execSql, fields, nil
+}
+
+func execInsertStruct(exec Execer, ctx context.Context, execSql string, args []interface{}, fields *reflectInsertField) (sql.Result, error) {
 	// log.Debugf("%s%+v", execSql, vals)
-	result, err := exec.ExecContext(ctx, execSql, fields.Values...)
+	result, err := exec.ExecContext(ctx, execSql, args...)
 	if err != nil {
-		return nil, errors.As(err, execSql)
+		return nil, err
 	}
 	if fields.AutoIncrement != nil {
 		id, _ := result.LastInsertId()
@@ -1,7 +1,7 @@
 package qsql
 
 import (
-	"fmt"
+	"context"
 	"reflect"
 	"strconv"
 	"strings"
@@ -17,6 +17,7 @@ type SelectBuilder struct {
 
 	indent string
 	dump   bool
+	redact *RedactPolicy // the redact policy of db for dump
 
 	queryStr    string
 	fromStr     string
@@ -43,8 +44,13 @@ func NewSelectBuilder(driverName string) *SelectBuilder {
 	return NewSelectBuilderWithIndent(" ", driverName)
 }
 
+// the dump args are redacted by the policy of db when the drv is qsql.DB.
 func NewSelectBuidler(drv BuilderDriver) *SelectBuilder {
-	return NewSelectBuilder(drv.DriverName())
+	b := NewSelectBuilder(drv.DriverName())
+	if db, ok := drv.(*DB); ok {
+		b.redact = db.options().redact
+	}
+	return b
 }
 
 func (b *SelectBuilder) SetDump(dump bool) *SelectBuilder {
@@ -69,6 +75,7 @@ func (b *SelectBuilder) Copy(newSelectBuffer bool) *SelectBuilder {
 		driver:      b.driver,
 		indent:      b.indent,
 		dump:        b.dump,
+		redact:      b.redact,
 		queryStr:    queryStr,
 		fromStr:     b.fromStr,
 		fromArgs:    make([]interface{}, len(b.fromArgs)),
@@ -132,6 +139,7 @@ func (b *SelectBuilder) From(query string, args ...interface{}) *SelectBuilder {
 	return b
 }
 
+// the args can be a struct or a map[string]interface{} for the ':name' params of query.
 func (b *SelectBuilder) IfWhere(add bool, query string, args ...interface{}) *SelectBuilder {
 	if !add {
 		return b
@@ -139,6 +147,16 @@ func (b *SelectBuilder) IfWhere(add bool, query string, args ...interface{}) *Se
 	if len(query) == 0 {
 		return b
 	}
+	if getArg, ok := namedArgOf(args); ok && hasNamed(b.driver, query) {
+		// bind the ':name' params to '?', it will be translated to the driver in the end.
+		namedSql, namedArgs, err := bindNamed(b.driver, query, getArg, func(int) string {
+			return "?"
+		})
+		if err != nil {
+			panic(err)
+		}
+		query, args = namedSql, namedArgs
+	}
 	if len(b.whereStr) > 0 {
 		b.whereStr += b.Indent()
 	}
@@ -169,19 +187,13 @@ func (b *SelectBuilder) IfWhereIn(add bool, inQuery string, sliceArgs interface{
 	if v.Len() == 0 {
 		panic("need arguments of in condition")
 	}
-	stmtIn := make([]rune, v.Len()*2)
 	args := make([]interface{}, v.Len())
 	for i := v.Len() - 1; i > -1; i-- {
-		stmtIn[i*2] = '?'
-		stmtIn[i*2+1] = ','
 		args[i] = v.Index(i).Interface()
 	}
-	sqlStr := strings.Replace(inQuery, "IN ?", "IN ("+string(stmtIn[:len(stmtIn)-1])+")", 1)
-	if sqlStr == inQuery {
-		sqlStr = strings.Replace(inQuery, "in ?", "IN ("+string(stmtIn[:len(stmtIn)-1])+")", 1)
-		if sqlStr == inQuery {
-			panic("'IN ?' format not found: " + inQuery)
-		}
+	sqlStr, ok := expandIn(b.driver, inQuery, len(args))
+	if !ok {
+		panic("'IN ?' format not found: " + inQuery)
 	}
 	return b.IfWhere(add, sqlStr, args...)
 }
@@ -293,48 +305,9 @@ func (b *SelectBuilder) String() string {
 	}
 
 	// translate to db driver
-	switch b.driver {
-	case DRV_NAME_ORACLE, _DRV_NAME_OCI8:
-		paramIdx := 1
-		buff := strings.Builder{}
-		for _, r := range sqlStr {
-			if r != '?' {
-				buff.WriteRune(r)
-			} else {
-				buff.WriteString(fmt.Sprintf(":%d", paramIdx))
-				paramIdx++
-			}
-		}
-		sqlStr = buff.String()
-	case DRV_NAME_POSTGRES:
-		paramIdx := 1
-		buff := strings.Builder{}
-		for _, r := range sqlStr {
-			if r != '?' {
-				buff.WriteRune(r)
-			} else {
-				buff.WriteString(fmt.Sprintf("$%d", paramIdx))
-				paramIdx++
-			}
-		}
-		sqlStr = buff.String()
-	case DRV_NAME_SQLSERVER, _DRV_NAME_MSSQL:
-		buff := strings.Builder{}
-		paramIdx := 1
-		for _, r := range sqlStr {
-			if r != '?' {
-				buff.WriteRune(r)
-			} else {
-				buff.WriteString(fmt.Sprintf("@p%d", paramIdx))
-				paramIdx++
-			}
-		}
-		sqlStr = buff.String()
-	default:
-		// nothing to do.
-	}
+	sqlStr = rebind(b.driver, sqlStr)
 	if b.dump {
-		log.Println(sqlStr, b.Args())
+		log.Info(context.TODO(), "dump sql", "driver", b.driver, "sql", sqlStr, "args", redactArgs(b.redact, b.driver, sqlStr, b.Args(), nil))
 	}
 	return sqlStr
 }
@@ -0,0 +1,4 @@
+package qsql
+
+// The sqlite driver for the tests with a real db.
+import _ "modernc.org/sqlite"
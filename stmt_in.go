@@ -1,7 +1,13 @@
 package qsql
 
 import (
+	"database/sql/driver"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gwaylib/errors"
 )
 
 func stmtIn(paramIdx, paramsLen int, driverNames ...string) string {
@@ -51,3 +57,170 @@ func stmtIn(paramIdx, paramsLen int, driverNames ...string) string {
 		return string(result)
 	}
 }
+
+// Replace the first 'IN ?' of sql with 'IN (?,?,...)' for the paramsLen args, return false if not found.
+// The 'IN ?' in the strings, quoted identifiers and comments are ignored.
+func expandIn(drvName, inQuery string, paramsLen int) (string, bool) {
+	toks := lexSql(drvName, inQuery)
+	for i, tok := range toks {
+		if i == 0 || tok.kind != sqlPlaceholder || tok.text != "?" || toks[i-1].kind != sqlText {
+			continue
+		}
+		prev := strings.TrimRight(toks[i-1].text, " \t\r\n")
+		if len(prev) == len(toks[i-1].text) || len(prev) < 2 || !strings.EqualFold(prev[len(prev)-2:], "IN") {
+			continue
+		}
+		if len(prev) > 2 && isSqlIdent(prev[len(prev)-3]) {
+			continue
+		}
+		buff := strings.Builder{}
+		for _, t := range toks[:i] {
+			buff.WriteString(t.text)
+		}
+		buff.WriteString("(?" + strings.Repeat(",?", paramsLen-1) + ")")
+		for _, t := range toks[i+1:] {
+			buff.WriteString(t.text)
+		}
+		return buff.String(), true
+	}
+	return inQuery, false
+}
+
+// Return is it a slice arg to expand, the []byte and driver.Valuer are scalar.
+func isSliceArg(arg interface{}) bool {
+	switch arg.(type) {
+	case nil, []byte, driver.Valuer:
+		return false
+	}
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
+
+// Return the placeholder is after 'IN (' or 'IN'.
+func isInPlaceholder(prev string) (paren bool, ok bool) {
+	prev = strings.TrimRight(prev, " \t\r\n")
+	if strings.HasSuffix(prev, "(") {
+		paren = true
+		prev = strings.TrimRight(prev[:len(prev)-1], " \t\r\n")
+	}
+	if len(prev) < 2 || !strings.EqualFold(prev[len(prev)-2:], "IN") {
+		return false, false
+	}
+	if len(prev) > 2 && isSqlIdent(prev[len(prev)-3]) {
+		return false, false
+	}
+	return paren, true
+}
+
+// Expand the slice args bound to 'IN (?)' or 'IN ?' to the placeholders of every element,
+// the numbered placeholders like '$1', ':1', '@p1' are renumbered with the expanded args.
+// The origins is the index of the source arg for every arg returned, it's nil when no slice arg.
+func expandInArgs(drvName, querySql string, args []interface{}) (string, []interface{}, []int, error) {
+	hasSlice := false
+	for _, arg := range args {
+		if isSliceArg(arg) {
+			hasSlice = true
+			break
+		}
+	}
+	if !hasSlice {
+		return querySql, args, nil, nil
+	}
+
+	toks := lexSql(drvName, querySql)
+	// the arg index of every placeholder, -1 if it's not a placeholder or out of args.
+	argIdxs := make([]int, len(toks))
+	expands := make([]bool, len(args))
+	seq := 0
+	for i, tok := range toks {
+		argIdxs[i] = -1
+		if tok.kind != sqlPlaceholder {
+			continue
+		}
+		idx := seq
+		if tok.text == "?" {
+			seq++
+		} else {
+			n, _ := strconv.Atoi(strings.TrimLeft(tok.text, "?$:@pP"))
+			idx = n - 1
+		}
+		if idx < 0 || idx >= len(args) {
+			continue
+		}
+		argIdxs[i] = idx
+		if i > 0 && toks[i-1].kind == sqlText && isSliceArg(args[idx]) {
+			if _, ok := isInPlaceholder(toks[i-1].text); ok {
+				expands[idx] = true
+			}
+		}
+	}
+
+	// the new index of args start from 1
+	newIdxs := make([]int, len(args))
+	newArgs := []interface{}{}
+	origins := []int{}
+	for i, arg := range args {
+		newIdxs[i] = len(newArgs) + 1
+		if !expands[i] {
+			newArgs = append(newArgs, arg)
+			origins = append(origins, i)
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		if v.Len() == 0 {
+			return "", nil, nil, errors.New("need arguments of in condition").As(i, querySql)
+		}
+		for j := 0; j < v.Len(); j++ {
+			newArgs = append(newArgs, v.Index(j).Interface())
+			origins = append(origins, i)
+		}
+	}
+
+	buff := strings.Builder{}
+	for i, tok := range toks {
+		idx := argIdxs[i]
+		if idx < 0 {
+			buff.WriteString(tok.text)
+			continue
+		}
+		// keep the style of placeholder
+		placeholder := func(newIdx int) string {
+			if tok.text == "?" {
+				return "?"
+			}
+			return strings.TrimRight(tok.text, "0123456789") + strconv.Itoa(newIdx)
+		}
+		if !expands[idx] {
+			buff.WriteString(placeholder(newIdxs[idx]))
+			continue
+		}
+		paren, _ := isInPlaceholder(toks[i-1].text)
+		if !paren {
+			buff.WriteString("(")
+		}
+		for j := 0; j < reflect.ValueOf(args[idx]).Len(); j++ {
+			if j > 0 {
+				buff.WriteString(",")
+			}
+			buff.WriteString(placeholder(newIdxs[idx] + j))
+		}
+		if !paren {
+			buff.WriteString(")")
+		}
+	}
+	return buff.String(), newArgs, origins, nil
+}
+
+// Expand the slice args of operation bound to 'IN (?)'.
+func expandInOp(op *Operation) error {
+	querySql, args, origins, err := expandInArgs(op.Driver, op.Sql, op.Args)
+	if err != nil {
+		return err
+	}
+	op.Sql, op.Args, op.argOrigins = querySql, args, origins
+	return nil
+}
@@ -24,3 +24,68 @@ func TestStmtIn(t *testing.T) {
 		t.Fatalf("expect '@p1,@p2,@p3', but@ %s", msOutput1)
 	}
 }
+
+func TestExpandInArgs(t *testing.T) {
+	cases := []struct {
+		drvName string
+		sql     string
+		args    []interface{}
+		expect  string
+		argsLen int
+	}{
+		{DRV_NAME_MYSQL, "SELECT * FROM t WHERE a=? AND id IN (?) AND b=?", []interface{}{1, []int{1, 2, 3}, 2}, "SELECT * FROM t WHERE a=? AND id IN (?,?,?) AND b=?", 5},
+		{DRV_NAME_MYSQL, "SELECT * FROM t WHERE id in ? AND data=?", []interface{}{[]string{"a", "b"}, []byte("c")}, "SELECT * FROM t WHERE id in (?,?) AND data=?", 3},
+		{DRV_NAME_POSTGRES, "SELECT * FROM t WHERE a=$1 AND id IN ($2) AND b=$3 AND c=$1", []interface{}{1, []int{1, 2, 3}, 2}, "SELECT * FROM t WHERE a=$1 AND id IN ($2,$3,$4) AND b=$5 AND c=$1", 5},
+		{DRV_NAME_ORACLE, "SELECT * FROM t WHERE id IN (:1) AND b=:2", []interface{}{[]int64{1, 2}, 2}, "SELECT * FROM t WHERE id IN (:1,:2) AND b=:3", 3},
+		{DRV_NAME_SQLSERVER, "SELECT * FROM t WHERE a=@p1 AND id IN (@p2)", []interface{}{1, []int{1, 2}}, "SELECT * FROM t WHERE a=@p1 AND id IN (@p2,@p3)", 3},
+		{DRV_NAME_MYSQL, "SELECT * FROM t WHERE note='IN (?)' AND a=?", []interface{}{[]int{1}}, "SELECT * FROM t WHERE note='IN (?)' AND a=?", 1},
+		// the user variable of mysql
+		{DRV_NAME_MYSQL, "SELECT * FROM t WHERE a = @p1 AND b IN (?)", []interface{}{[]int{4, 5}}, "SELECT * FROM t WHERE a = @p1 AND b IN (?,?)", 2},
+	}
+	for _, c := range cases {
+		result, args, _, err := expandInArgs(c.drvName, c.sql, c.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != c.expect || len(args) != c.argsLen {
+			t.Fatalf("%s: expect %s with %d args, but: %s %+v", c.drvName, c.expect, c.argsLen, result, args)
+		}
+	}
+	if _, _, _, err := expandInArgs(DRV_NAME_MYSQL, "SELECT * FROM t WHERE id IN (?)", []interface{}{[]int{}}); err == nil {
+		t.Fatal("expect error of empty args")
+	}
+}
+
+func TestQueryIn(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+	for _, name := range []string{"t1", "t2", "t3"} {
+		if _, err := mdb.Exec("INSERT INTO user (username) VALUES (?)", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := []string{}
+	if err := mdb.QueryElems(&names, "SELECT username FROM user WHERE id IN (?) AND username<>? ORDER BY id", []int64{1, 2, 3}, "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "t1" || names[1] != "t3" {
+		t.Fatalf("unexpect names: %+v", names)
+	}
+	// the numbered params of sqlite
+	names = names[:0]
+	if err := mdb.QueryElems(&names, "SELECT username FROM user WHERE username<>?2 AND id IN (?1) ORDER BY id", []int64{1, 2, 3}, "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "t1" || names[1] != "t3" {
+		t.Fatalf("unexpect numbered names: %+v", names)
+	}
+
+	result, err := mdb.Exec("DELETE FROM user WHERE username IN (?)", []string{"t1", "t3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := result.RowsAffected(); n != 2 {
+		t.Fatalf("expect 2 deleted, but: %d", n)
+	}
+}
@@ -0,0 +1,250 @@
+package qsql
+
+import "strings"
+
+type sqlTokenKind int
+
+const (
+	sqlText        sqlTokenKind = iota // the keywords, identifiers, operators and spaces
+	sqlString                          // the string literal like 'a', or "a" of mysql
+	sqlQuotedIdent                     // the quoted identifier like "a", `a`, [a]
+	sqlComment                         // -- a, /* a */, # a of mysql
+	sqlPlaceholder                     // ?, $1, :1, @p1, ?1 of sqlite
+	sqlNamed                           // :name, @name and $name of sqlite
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// The syntax of driver for splitting the sql.
+type sqlDialect struct {
+	doubleQuoteString bool // "a" is a string
+	backslashEscape   bool // 'a\'b' is a string
+	backtick          bool // `a` is an identifier
+	bracket           bool // [a] is an identifier
+	hashComment       bool // # a is a comment
+	nestedComment     bool // /* a /* b */ */ is a comment
+	dollarQuote       bool // $$a$$ and $tag$a$tag$ are strings, E'a\'b' is a string
+	jsonOperator      bool // ?| and ?& are the operators of jsonb, and ? is too when the sql uses $1
+	dollarNumber      bool // $1 is a placeholder
+	atNumber          bool // @p1 is a placeholder
+	colonNumber       bool // :1 is a placeholder
+	questionNumber    bool // ?1 is a placeholder
+	atDollarNamed     bool // @name and $name are the named params
+	arraySlice        bool // a[1:n] is the slice of array, the ':n' in the subscript is not a named param, but ARRAY[:n] is
+}
+
+func dialectOf(drvName string) sqlDialect {
+	switch drvName {
+	case DRV_NAME_MYSQL:
+		return sqlDialect{doubleQuoteString: true, backslashEscape: true, backtick: true, hashComment: true}
+	case DRV_NAME_POSTGRES:
+		return sqlDialect{nestedComment: true, dollarQuote: true, jsonOperator: true, dollarNumber: true, arraySlice: true}
+	case DRV_NAME_SQLSERVER, _DRV_NAME_MSSQL:
+		return sqlDialect{bracket: true, atNumber: true}
+	case DRV_NAME_ORACLE, _DRV_NAME_OCI8:
+		return sqlDialect{colonNumber: true}
+	default:
+		// sqlite3 and the unknow drivers
+		return sqlDialect{backtick: true, bracket: true, questionNumber: true, atDollarNamed: true}
+	}
+}
+
+func isSqlIdent(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSqlDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isSqlIdentStart(c byte) bool {
+	return isSqlIdent(c) && !isSqlDigit(c)
+}
+
+// Split the sql to tokens by the dialect of driver, the joined text of tokens is the sql.
+func lexSql(drvName, s string) []sqlToken {
+	d := dialectOf(drvName)
+	toks := []sqlToken{}
+	textStart := 0
+	emit := func(kind sqlTokenKind, i, j int) int {
+		if i > textStart {
+			toks = append(toks, sqlToken{kind: sqlText, text: s[textStart:i]})
+		}
+		toks = append(toks, sqlToken{kind: kind, text: s[i:j]})
+		textStart = j
+		return j
+	}
+	// return the end of s[i:] which matches fn
+	span := func(i int, fn func(c byte) bool) int {
+		for i < len(s) && fn(s[i]) {
+			i++
+		}
+		return i
+	}
+	// return the end of the quoted s[i:] start with the quote
+	quoteEnd := func(i int, quote byte, backslash bool) int {
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '\\':
+				if backslash {
+					j++
+				}
+			case quote:
+				if j+1 < len(s) && s[j+1] == quote {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(s)
+	}
+	// return the end of the text start with prefix and end with suffix, or len(s) if not closed
+	indexEnd := func(i int, prefix, suffix string) int {
+		j := strings.Index(s[i+len(prefix):], suffix)
+		if j < 0 {
+			return len(s)
+		}
+		return i + len(prefix) + j + len(suffix)
+	}
+
+	subscripts := []bool{} // the brackets are the subscript of array or not
+	for i := 0; i < len(s); {
+		c := s[i]
+		next := byte(0)
+		if i+1 < len(s) {
+			next = s[i+1]
+		}
+		switch {
+		case c == '\'':
+			// E'a\'b' of postgres
+			eString := d.dollarQuote && i > 0 && (s[i-1] == 'E' || s[i-1] == 'e') && (i < 2 || !isSqlIdent(s[i-2]))
+			i = emit(sqlString, i, quoteEnd(i, c, d.backslashEscape || eString))
+		case c == '"':
+			if d.doubleQuoteString {
+				i = emit(sqlString, i, quoteEnd(i, c, d.backslashEscape))
+			} else {
+				i = emit(sqlQuotedIdent, i, quoteEnd(i, c, false))
+			}
+		case c == '`' && d.backtick:
+			i = emit(sqlQuotedIdent, i, quoteEnd(i, c, false))
+		case c == '[' && d.bracket:
+			i = emit(sqlQuotedIdent, i, quoteEnd(i, ']', false))
+		case c == '-' && next == '-', c == '#' && d.hashComment:
+			i = emit(sqlComment, i, indexEnd(i, "", "\n"))
+		case c == '/' && next == '*':
+			if !d.nestedComment {
+				i = emit(sqlComment, i, indexEnd(i, "/*", "*/"))
+				continue
+			}
+			j, depth := i, 0
+			for j < len(s) {
+				if strings.HasPrefix(s[j:], "/*") {
+					depth++
+					j += 2
+				} else if strings.HasPrefix(s[j:], "*/") {
+					depth--
+					j += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					j++
+				}
+			}
+			i = emit(sqlComment, i, j)
+		case c == '?':
+			if d.jsonOperator && (next == '|' || next == '&') {
+				i += 2
+				continue
+			}
+			if d.questionNumber && isSqlDigit(next) {
+				i = emit(sqlPlaceholder, i, span(i+1, isSqlDigit))
+				continue
+			}
+			i = emit(sqlPlaceholder, i, i+1)
+		case (c == '@' || c == '$') && d.atDollarNamed && isSqlIdentStart(next) && (i == 0 || !isSqlIdent(s[i-1])):
+			i = emit(sqlNamed, i, span(i+1, isSqlIdent))
+		case c == '[' && d.arraySlice:
+			subscripts = append(subscripts, isSubscript(s[:i]))
+			i++
+		case c == ']' && d.arraySlice:
+			if len(subscripts) > 0 {
+				subscripts = subscripts[:len(subscripts)-1]
+			}
+			i++
+		case c == '$' && d.dollarNumber && isSqlDigit(next):
+			i = emit(sqlPlaceholder, i, span(i+1, isSqlDigit))
+		case c == '$' && d.dollarQuote && (i == 0 || !isSqlIdent(s[i-1])):
+			// $$a$$, $tag$a$tag$
+			j := span(i+1, isSqlIdent)
+			if j >= len(s) || s[j] != '$' {
+				i++
+				continue
+			}
+			i = emit(sqlString, i, indexEnd(i, s[i:j+1], s[i:j+1]))
+		case c == '@' && d.atNumber && (next == 'p' || next == 'P') && i+2 < len(s) && isSqlDigit(s[i+2]):
+			i = emit(sqlPlaceholder, i, span(i+2, isSqlDigit))
+		case c == ':' && (i == 0 || s[i-1] != ':') && isSqlIdent(next) && (len(subscripts) == 0 || !subscripts[len(subscripts)-1]):
+			// :1, :name, but not the '::' cast of postgres
+			if !isSqlDigit(next) {
+				i = emit(sqlNamed, i, span(i+1, isSqlIdent))
+			} else if d.colonNumber {
+				i = emit(sqlPlaceholder, i, span(i+1, isSqlDigit))
+			} else {
+				i++
+			}
+		default:
+			i++
+		}
+	}
+	if textStart < len(s) {
+		toks = append(toks, sqlToken{kind: sqlText, text: s[textStart:]})
+	}
+	if d.jsonOperator {
+		jsonQuestion(toks)
+	}
+	return toks
+}
+
+// Checking the '[' following the sql is a subscript of array like a[1], (a)[1], a[1][2], but not the ARRAY[1] constructor.
+func isSubscript(s string) bool {
+	s = strings.TrimRight(s, " \t\r\n")
+	if len(s) == 0 {
+		return false
+	}
+	switch c := s[len(s)-1]; {
+	case c == ')' || c == ']' || c == '"':
+		return true
+	case !isSqlIdent(c):
+		return false
+	}
+	i := len(s)
+	for i > 0 && isSqlIdent(s[i-1]) {
+		i--
+	}
+	return !strings.EqualFold(s[i:], "ARRAY")
+}
+
+// The '?' is the jsonb operator of postgres when the sql uses the $1 placeholders,
+// using the jsonb_exists function instead of it for the '?' placeholders.
+func jsonQuestion(toks []sqlToken) {
+	numbered := false
+	for _, tok := range toks {
+		if tok.kind == sqlPlaceholder && tok.text != "?" {
+			numbered = true
+			break
+		}
+	}
+	if !numbered {
+		return
+	}
+	for i := range toks {
+		if toks[i].kind == sqlPlaceholder && toks[i].text == "?" {
+			toks[i].kind = sqlText
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package qsql
+
+import (
+	"strings"
+	"testing"
+)
+
+type lexTestCase struct {
+	sql          string
+	rebind       string
+	placeholders int
+	named        int
+}
+
+// the corpus of the drivers in drv.go
+var lexTestCorpus = map[string][]lexTestCase{
+	DRV_NAME_MYSQL: {
+		{"SELECT * FROM t WHERE a=? AND b=?", "SELECT * FROM t WHERE a=? AND b=?", 2, 0},
+		{`SELECT '?', "?", 'it\'s ?', "a""?" FROM t WHERE a=?`, `SELECT '?', "?", 'it\'s ?', "a""?" FROM t WHERE a=?`, 1, 0},
+		{"SELECT `a?` FROM t # where ?\nWHERE a=? -- and ?\n/* ? */", "SELECT `a?` FROM t # where ?\nWHERE a=? -- and ?\n/* ? */", 1, 0},
+		// the user variables
+		{"SET @p1 = 1; SELECT * FROM t WHERE a=@p1 AND b=@var AND c=? AND d=:1 AND e=$1", "SET @p1 = 1; SELECT * FROM t WHERE a=@p1 AND b=@var AND c=? AND d=:1 AND e=$1", 1, 0},
+	},
+	DRV_NAME_POSTGRES: {
+		{"SELECT * FROM t WHERE a=? AND b=?", "SELECT * FROM t WHERE a=$1 AND b=$2", 2, 0},
+		{`SELECT '?', "a?", 'it''s ?', E'it\'s ?' FROM t WHERE a=?`, `SELECT '?', "a?", 'it''s ?', E'it\'s ?' FROM t WHERE a=$1`, 1, 0},
+		{"SELECT $$?$$, $tag$ ? $tag$ FROM t WHERE a=? /* ? /* ? */ ? */ -- ?", "SELECT $$?$$, $tag$ ? $tag$ FROM t WHERE a=$1 /* ? /* ? */ ? */ -- ?", 1, 0},
+		{"SELECT a::text FROM t WHERE data ?| ? AND data ?& ? AND ARRAY[?]", "SELECT a::text FROM t WHERE data ?| $1 AND data ?& $2 AND ARRAY[$3]", 3, 0},
+		{"SELECT * FROM t WHERE a=$1 AND b=$2", "SELECT * FROM t WHERE a=$1 AND b=$2", 2, 0},
+		// the jsonb operator with the $1 placeholders
+		{"SELECT * FROM t WHERE data ? 'a' AND data ?| $1 AND b=$2", "SELECT * FROM t WHERE data ? 'a' AND data ?| $1 AND b=$2", 2, 0},
+		{"SELECT * FROM t WHERE jsonb_exists(data, ?) AND b=@p1 AND c=:1 AND d[1:2]=?", "SELECT * FROM t WHERE jsonb_exists(data, $1) AND b=@p1 AND c=:1 AND d[1:2]=$2", 2, 0},
+		// the slice of array, but the named params of the ARRAY constructor
+		{"SELECT a[1:n], a[x:y], a [:n], (a)[1:n], a[1][b:c] FROM t WHERE b=? AND c=ARRAY[:c] AND d=:d", "SELECT a[1:n], a[x:y], a [:n], (a)[1:n], a[1][b:c] FROM t WHERE b=$1 AND c=ARRAY[:c] AND d=:d", 1, 2},
+	},
+	DRV_NAME_SQLITE3: {
+		{"SELECT * FROM t WHERE a=? AND b=?", "SELECT * FROM t WHERE a=? AND b=?", 2, 0},
+		{"SELECT * FROM t WHERE a=@p1 AND b=$1 AND c=:1 AND d=?", "SELECT * FROM t WHERE a=@p1 AND b=$1 AND c=:1 AND d=?", 1, 1},
+		// the numbered and named params of sqlite
+		{"SELECT * FROM t WHERE a=?1 AND b=?2 AND c=?1", "SELECT * FROM t WHERE a=?1 AND b=?2 AND c=?1", 3, 0},
+		{"SELECT * FROM t WHERE a=:a AND b=@b AND c=$c AND d=$e::f AND e=? AND 'x@y'", "SELECT * FROM t WHERE a=:a AND b=@b AND c=$c AND d=$e::f AND e=? AND 'x@y'", 1, 4},
+		{"SELECT '?', \"?\", `?`, [?] FROM t WHERE a=? -- ?", "SELECT '?', \"?\", `?`, [?] FROM t WHERE a=? -- ?", 1, 0},
+	},
+	DRV_NAME_SQLSERVER: {
+		{"SELECT * FROM t WHERE a=? AND b=?", "SELECT * FROM t WHERE a=@p1 AND b=@p2", 2, 0},
+		{"SELECT '?', \"?\", [a?] FROM t WHERE a=? /* ? */", "SELECT '?', \"?\", [a?] FROM t WHERE a=@p1 /* ? */", 1, 0},
+		{"SELECT * FROM t WHERE a=@p1 AND b=@price", "SELECT * FROM t WHERE a=@p1 AND b=@price", 1, 0},
+		{"SELECT * FROM t WHERE a=$1 AND b=:1", "SELECT * FROM t WHERE a=$1 AND b=:1", 0, 0},
+	},
+	DRV_NAME_ORACLE: {
+		{"SELECT * FROM t WHERE a=? AND b=?", "SELECT * FROM t WHERE a=:1 AND b=:2", 2, 0},
+		{"SELECT '?', \"?\" FROM t WHERE a=? -- ?", "SELECT '?', \"?\" FROM t WHERE a=:1 -- ?", 1, 0},
+		{"SELECT * FROM t WHERE a=:1 AND b=:2", "SELECT * FROM t WHERE a=:1 AND b=:2", 2, 0},
+		{"SELECT * FROM t WHERE a=$1 AND b=@p1", "SELECT * FROM t WHERE a=$1 AND b=@p1", 0, 0},
+	},
+}
+
+func TestLexSql(t *testing.T) {
+	for drvName, cases := range lexTestCorpus {
+		for _, c := range cases {
+			toks := lexSql(drvName, c.sql)
+			joined := strings.Builder{}
+			placeholders, named := 0, 0
+			for _, tok := range toks {
+				joined.WriteString(tok.text)
+				switch tok.kind {
+				case sqlPlaceholder:
+					placeholders++
+				case sqlNamed:
+					named++
+				}
+			}
+			if joined.String() != c.sql {
+				t.Fatalf("%s: expect joined %s, but: %s", drvName, c.sql, joined.String())
+			}
+			if placeholders != c.placeholders {
+				t.Fatalf("%s: %s expect %d placeholders, but: %d", drvName, c.sql, c.placeholders, placeholders)
+			}
+			if named != c.named {
+				t.Fatalf("%s: %s expect %d named params, but: %d", drvName, c.sql, c.named, named)
+			}
+			if result := rebind(drvName, c.sql); result != c.rebind {
+				t.Fatalf("%s: expect rebind %s, but: %s", drvName, c.rebind, result)
+			}
+		}
+	}
+}
+
+func TestExpandIn(t *testing.T) {
+	cases := []struct {
+		drvName string
+		sql     string
+		expect  string
+		ok      bool
+	}{
+		{DRV_NAME_MYSQL, "AND id IN ?", "AND id IN (?,?,?)", true},
+		{DRV_NAME_MYSQL, "OR (id in\n?)", "OR (id in\n(?,?,?))", true},
+		{DRV_NAME_MYSQL, "AND note='IN ?' AND id IN ?", "AND note='IN ?' AND id IN (?,?,?)", true},
+		{DRV_NAME_POSTGRES, "AND data ?| ARRAY['a'] AND id IN ?", "AND data ?| ARRAY['a'] AND id IN (?,?,?)", true},
+		{DRV_NAME_MYSQL, "AND login ?", "AND login ?", false},
+		{DRV_NAME_MYSQL, "AND id IN (?)", "AND id IN (?)", false},
+	}
+	for _, c := range cases {
+		result, ok := expandIn(c.drvName, c.sql, 3)
+		if result != c.expect || ok != c.ok {
+			t.Fatalf("%s: expect %s %t, but: %s %t", c.sql, c.expect, c.ok, result, ok)
+		}
+	}
+}
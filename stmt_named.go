@@ -0,0 +1,114 @@
+package qsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gwaylib/errors"
+)
+
+var (
+	ErrNamedArgNotFound = errors.New("named arg not found")
+)
+
+// Return the value getter of the named args when the args is only a struct or a map[string]interface{},
+// the struct field is named by the 'db' tag like the QueryStruct.
+func namedArgOf(args []interface{}) (func(name string) (interface{}, bool), bool) {
+	if len(args) != 1 || args[0] == nil {
+		return nil, false
+	}
+	switch arg := args[0].(type) {
+	case map[string]interface{}:
+		return func(name string) (interface{}, bool) {
+			val, ok := arg[name]
+			return val, ok
+		}, true
+	case driver.Valuer, time.Time, *time.Time, sql.NamedArg, *sql.NamedArg:
+		// the value of driver
+		return nil, false
+	}
+
+	v := reflect.ValueOf(args[0])
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, false
+	}
+	tm := refxM.TypeMap(v.Type())
+	return func(name string) (interface{}, bool) {
+		fi := tm.GetByPath(name)
+		if fi == nil {
+			return nil, false
+		}
+		fv := v
+		for _, i := range fi.Index {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					// the field of a nil struct
+					return nil, true
+				}
+				fv = fv.Elem()
+			}
+			fv = fv.Field(i)
+		}
+		return fv.Interface(), true
+	}, true
+}
+
+// Replace the ':name' params of sql with the placeholders made by the index of args from 1, and return the args by order.
+// The other text of sql is kept, like the '::' cast and '?' operator of postgres, the ':1' of oracle, the strings and comments.
+func bindNamed(drvName, querySql string, getArg func(name string) (interface{}, bool), placeholder func(idx int) string) (string, []interface{}, error) {
+	buff := strings.Builder{}
+	args := []interface{}{}
+	for _, tok := range lexSql(drvName, querySql) {
+		if tok.kind != sqlNamed {
+			buff.WriteString(tok.text)
+			continue
+		}
+		name := tok.text[1:]
+		val, ok := getArg(name)
+		if !ok {
+			return "", nil, ErrNamedArgNotFound.As(name, querySql)
+		}
+		args = append(args, val)
+		buff.WriteString(placeholder(len(args)))
+	}
+	return buff.String(), args, nil
+}
+
+// Checking the sql has the ':name' params, or the '@name' and '$name' of sqlite.
+func hasNamed(drvName, querySql string) bool {
+	if !strings.ContainsAny(querySql, ":@$") {
+		return false
+	}
+	for _, tok := range lexSql(drvName, querySql) {
+		if tok.kind == sqlNamed {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind the named args of operation when the args is only a struct or a map[string]interface{},
+// and the sql has the ':name' params.
+func bindNamedOp(op *Operation) error {
+	getArg, ok := namedArgOf(op.Args)
+	if !ok || !hasNamed(op.Driver, op.Sql) {
+		return nil
+	}
+	querySql, args, err := bindNamed(op.Driver, op.Sql, getArg, func(idx int) string {
+		return bindVar(op.Driver, idx)
+	})
+	if err != nil {
+		return err
+	}
+	op.Sql, op.Args = querySql, args
+	return nil
+}
@@ -0,0 +1,168 @@
+package qsql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestBindNamed(t *testing.T) {
+	arg := map[string]interface{}{"id": 1, "name": "t1"}
+	getArg, ok := namedArgOf([]interface{}{arg})
+	if !ok {
+		t.Fatal("expect named arg")
+	}
+	cases := []struct {
+		drvName string
+		sql     string
+		expect  string
+		args    int
+	}{
+		{DRV_NAME_MYSQL, "SELECT * FROM user WHERE id=:id AND (username=:name OR nick=:name)", "SELECT * FROM user WHERE id=? AND (username=? OR nick=?)", 3},
+		{DRV_NAME_POSTGRES, "SELECT id::text FROM user WHERE id=:id AND note<>':name' -- :name\n AND username=:name", "SELECT id::text FROM user WHERE id=$1 AND note<>':name' -- :name\n AND username=$2", 2},
+		{DRV_NAME_ORACLE, "SELECT * FROM user WHERE id=:id AND username=:name", "SELECT * FROM user WHERE id=:1 AND username=:2", 2},
+		{DRV_NAME_SQLSERVER, "SELECT [:id] FROM user /* :id */ WHERE id=:id", "SELECT [:id] FROM user /* :id */ WHERE id=@p1", 1},
+		// the '?' operator of postgres jsonb is not a placeholder
+		{DRV_NAME_POSTGRES, "SELECT * FROM t WHERE data ? 'a' AND id=:id", "SELECT * FROM t WHERE data ? 'a' AND id=$1", 1},
+	}
+	for _, c := range cases {
+		querySql, args, err := bindNamed(c.drvName, c.sql, getArg, func(idx int) string {
+			return bindVar(c.drvName, idx)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if querySql != c.expect || len(args) != c.args {
+			t.Fatalf("%s: expect %s with %d args, but: %s, %+v", c.drvName, c.expect, c.args, querySql, args)
+		}
+	}
+
+	if _, _, err := bindNamed(DRV_NAME_MYSQL, "SELECT * FROM user WHERE id=:uid", getArg, func(int) string { return "?" }); !ErrNamedArgNotFound.Equal(err) {
+		t.Fatalf("expect not found, but: %v", err)
+	}
+	if _, ok := namedArgOf([]interface{}{1}); ok {
+		t.Fatal("expect not named arg")
+	}
+	if _, ok := namedArgOf([]interface{}{sql.Named("id", 1)}); ok {
+		t.Fatal("expect the sql.NamedArg is passed to driver")
+	}
+
+	// not rewrite without the ':name' params
+	op := &Operation{Driver: DRV_NAME_POSTGRES, Sql: "SELECT ? FROM user WHERE note<>':name'", Args: []interface{}{arg}}
+	if err := bindNamedOp(op); err != nil {
+		t.Fatal(err)
+	}
+	if op.Sql != "SELECT ? FROM user WHERE note<>':name'" || len(op.Args) != 1 {
+		t.Fatalf("expect not rewrite, but: %s, %+v", op.Sql, op.Args)
+	}
+
+	// the driver of a raw sql.DB, it's not connected until used.
+	pgdb, err := sql.Open("postgres", "host=127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pgdb.Close()
+	op = newOp(runnerOf("", pgdb), "QueryElem", "SELECT * FROM user WHERE id=:id", []interface{}{arg})
+	if err := bindNamedOp(op); err != nil {
+		t.Fatal(err)
+	}
+	if op.Driver != DRV_NAME_POSTGRES || op.Sql != "SELECT * FROM user WHERE id=$1" {
+		t.Fatalf("expect bound by postgres, but: %s, %s", op.Driver, op.Sql)
+	}
+	op = &Operation{Driver: DRV_NAME_POSTGRES, Sql: "SELECT * FROM t WHERE data ? 'a' AND id=:id", Args: []interface{}{arg}}
+	if err := bindNamedOp(op); err != nil {
+		t.Fatal(err)
+	}
+	if op.Sql != "SELECT * FROM t WHERE data ? 'a' AND id=$1" || len(op.Args) != 1 {
+		t.Fatalf("expect the '?' operator kept, but: %s, %+v", op.Sql, op.Args)
+	}
+}
+
+type namedTestUser struct {
+	ID       int64  `db:"id"`
+	UserName string `db:"username"`
+}
+
+func TestNamedQuery(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	if _, err := mdb.Exec("INSERT INTO user (username) VALUES (:username)", &namedTestUser{UserName: "t1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mdb.Exec("INSERT INTO user (username) VALUES (:username)", map[string]interface{}{"username": "t2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	u := &namedTestUser{}
+	if err := mdb.QueryStruct(u, "SELECT id, username FROM user WHERE username=:username", namedTestUser{UserName: "t2"}); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 2 {
+		t.Fatalf("expect id 2, but: %+v", u)
+	}
+
+	count := 0
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>=:min AND id<=:max", map[string]interface{}{"min": 1, "max": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expect 2, but: %d", count)
+	}
+
+	_, data, err := mdb.QueryPageArr("SELECT username FROM user WHERE id=:id", map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || fmt.Sprint(data[0][0]) != "t1" {
+		t.Fatalf("unexpect data: %+v", data)
+	}
+
+	err = mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id=:uid", map[string]interface{}{"id": 1})
+	if !ErrNamedArgNotFound.Equal(err) {
+		t.Fatalf("expect not found, but: %v", err)
+	}
+	qErr := &QueryError{}
+	if !errors.As(err, &qErr) {
+		t.Fatalf("expect QueryError, but: %v", err)
+	}
+
+	// the '@name' and '$name' params of sqlite
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>=@min AND id<=$max", map[string]interface{}{"min": 2, "max": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expect 1, but: %d", count)
+	}
+
+	// the native named arg of driver
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user WHERE id>=:min", sql.Named("min", 2)); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expect 1, but: %d", count)
+	}
+
+	// bound by the driver of sql.DB
+	if drvName := runnerOf("", mdb.DB).DriverName(); drvName != DRV_NAME_SQLITE3 {
+		t.Fatalf("expect sqlite3, but: %s", drvName)
+	}
+	if err := QueryElem(mdb.DB, &count, "SELECT COUNT(*) FROM user WHERE id=:id", map[string]interface{}{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expect 1, but: %d", count)
+	}
+
+	bd := NewSelectBuidler(mdb).Select("username").From("user").
+		Where("id=:id", map[string]interface{}{"id": 2}).
+		Where("AND username<>:username", &namedTestUser{UserName: "t1"})
+	name := ""
+	if err := mdb.QueryElem(&name, bd.String(), bd.Args()...); err != nil {
+		t.Fatal(err)
+	}
+	if name != "t2" {
+		t.Fatalf("expect t2, but: %s", name)
+	}
+}
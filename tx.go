@@ -0,0 +1,355 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gwaylib/errors"
+)
+
+var (
+	// The error code of a panic recovered by Commit, it's returned when the db set SetCommitPanicAsError(true).
+	ErrCommitPanic = errors.New("commit panic")
+)
+
+// qsql.Tx Extend sql.Tx
+// and implement qsql.QuickSql interface
+type Tx struct {
+	*sql.Tx
+	drvName string
+	db      *DB             // nil when it's not begin from a qsql.DB
+	ctx     context.Context // the context of Commit, nil when it's not begin by Commit
+
+	savepointIdx int32
+
+	hookMu     sync.Mutex
+	onCommit   []func()
+	onRollback []func(error)
+
+	stmtMu sync.Mutex
+	stmts  map[string]*sql.Stmt // the cached stmts of db bound to the tx
+}
+
+func _checkQuickSqlTx() QuickSql {
+	return &Tx{}
+}
+
+// Wrap a *sql.Tx to qsql.Tx with the driver name.
+func NewTx(drvName string, tx *sql.Tx) *Tx {
+	return newTx(drvName, tx)
+}
+
+func newTx(drvName string, tx *sql.Tx) *Tx {
+	return &Tx{
+		Tx:      tx,
+		drvName: drvName,
+	}
+}
+
+func (tx *Tx) DriverName() string {
+	return tx.drvName
+}
+
+// Return the context of Commit for the methods without context.
+func (tx *Tx) context() context.Context {
+	if tx.ctx == nil {
+		return context.TODO()
+	}
+	return tx.ctx
+}
+
+// Same as sql.Tx.Exec, but intercepted by the interceptors of db.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return opExec(tx, tx.context(), query, args)
+}
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return opExec(tx, ctx, query, args)
+}
+
+// Same as sql.Tx.Query, but intercepted by the interceptors of db.
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return opQuery(tx, tx.context(), query, args)
+}
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return opQuery(tx, ctx, query, args)
+}
+
+// Same as sql.Tx.QueryRow, but intercepted by the interceptors of db.
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return opQueryRow(tx, tx.context(), query, args)
+}
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return opQueryRow(tx, ctx, query, args)
+}
+
+// Reflect one db data to the struct. the struct tag format like `db:"field_title"`, reference to: http://github.com/jmoiron/sqlx
+func (tx *Tx) InsertStruct(structPtr interface{}, tbName string) (sql.Result, error) {
+	return opInsertStruct(tx, tx.context(), structPtr, tbName)
+}
+func (tx *Tx) InsertStructContext(ctx context.Context, structPtr interface{}, tbName string) (sql.Result, error) {
+	return opInsertStruct(tx, ctx, structPtr, tbName)
+}
+
+// Reflect the sql.Rows to []struct array.
+// Return empty array if data not found.
+// Refere to: github.com/jmoiron/sqlx
+// DO NOT forget close the rows
+func (tx *Tx) ScanStructs(rows *sql.Rows, structsPtr interface{}) error {
+	return scanStructs(rows, structsPtr)
+}
+
+// Reflect the sql.Query result to a struct.
+func (tx *Tx) QueryStruct(structPtr interface{}, querySql string, args ...interface{}) error {
+	return opQueryStruct(tx, tx.context(), structPtr, querySql, args)
+}
+func (tx *Tx) QueryStructContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
+	return opQueryStruct(tx, ctx, structPtr, querySql, args)
+}
+
+// Reflect the sql.Query result to a struct array.
+// Return empty array if data not found.
+func (tx *Tx) QueryStructs(structPtr interface{}, querySql string, args ...interface{}) error {
+	return opQueryStructs(tx, tx.context(), structPtr, querySql, args)
+}
+func (tx *Tx) QueryStructsContext(ctx context.Context, structPtr interface{}, querySql string, args ...interface{}) error {
+	return opQueryStructs(tx, ctx, structPtr, querySql, args)
+}
+
+// Query one field to a sql.Scanner.
+func (tx *Tx) QueryElem(result interface{}, querySql string, args ...interface{}) error {
+	return opQueryElem(tx, tx.context(), result, querySql, args)
+}
+func (tx *Tx) QueryElemContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
+	return opQueryElem(tx, ctx, result, querySql, args)
+}
+
+// Query one field to a sql.Scanner array.
+func (tx *Tx) QueryElems(result interface{}, querySql string, args ...interface{}) error {
+	return opQueryElems(tx, tx.context(), result, querySql, args)
+}
+func (tx *Tx) QueryElemsContext(ctx context.Context, result interface{}, querySql string, args ...interface{}) error {
+	return opQueryElems(tx, ctx, result, querySql, args)
+}
+
+// Reflect the query result to a string array.
+func (tx *Tx) QueryPageArr(querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
+	return opQueryPageArr(tx, tx.context(), querySql, args)
+}
+func (tx *Tx) QueryPageArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]interface{}, err error) {
+	return opQueryPageArr(tx, ctx, querySql, args)
+}
+func (tx *Tx) QueryDBDataArr(querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
+	return opQueryDBDataArr(tx, tx.context(), querySql, args)
+}
+func (tx *Tx) QueryDBDataArrContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result [][]*DBData, err error) {
+	return opQueryDBDataArr(tx, ctx, querySql, args)
+}
+
+// Reflect the query result to a string map.
+func (tx *Tx) QueryPageMap(querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
+	return opQueryPageMap(tx, tx.context(), querySql, args)
+}
+func (tx *Tx) QueryPageMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]interface{}, err error) {
+	return opQueryPageMap(tx, ctx, querySql, args)
+}
+func (tx *Tx) QueryDBDataMap(querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
+	return opQueryDBDataMap(tx, tx.context(), querySql, args)
+}
+func (tx *Tx) QueryDBDataMapContext(ctx context.Context, querySql string, args ...interface{}) (titles []string, result []map[string]*DBData, err error) {
+	return opQueryDBDataMap(tx, ctx, querySql, args)
+}
+
+// Return "?,?,?,?..." for default, or "@p1,@p2,@p3..." for mssql, or ":1,:2,:3..." for pgsql.
+// paramStartIdx default is 0, but you need count it when the driver is mssq, pgsql etc. .
+func (tx *Tx) StmtIn(paramStartIdx, paramsLen int) string {
+	return stmtIn(paramStartIdx, paramsLen, tx.DriverName())
+}
+
+// Register a handler to run after the transaction is really committed.
+// It's discarded when the transaction or the savepoint registered in is rollback.
+func (tx *Tx) OnCommit(fn func()) {
+	tx.hookMu.Lock()
+	defer tx.hookMu.Unlock()
+	tx.onCommit = append(tx.onCommit, fn)
+}
+
+// Register a handler to run after the transaction or the savepoint registered in is rollback,
+// the handler input is the reason of rollback.
+func (tx *Tx) OnRollback(fn func(error)) {
+	tx.hookMu.Lock()
+	defer tx.hookMu.Unlock()
+	tx.onRollback = append(tx.onRollback, fn)
+}
+
+func (tx *Tx) hookMark() (int, int) {
+	tx.hookMu.Lock()
+	defer tx.hookMu.Unlock()
+	return len(tx.onCommit), len(tx.onRollback)
+}
+
+// Drop the handlers after the mark, and return the rollback handlers dropped.
+func (tx *Tx) hookReset(commitMark, rollbackMark int) []func(error) {
+	tx.hookMu.Lock()
+	defer tx.hookMu.Unlock()
+	onRollback := tx.onRollback[rollbackMark:]
+	tx.onCommit = tx.onCommit[:commitMark]
+	tx.onRollback = tx.onRollback[:rollbackMark:rollbackMark]
+	return onRollback
+}
+
+func (tx *Tx) afterCommit() {
+	onCommit, _ := tx.hooks()
+	for _, fn := range onCommit {
+		fn()
+	}
+}
+
+func (tx *Tx) afterRollback(err error) {
+	_, onRollback := tx.hooks()
+	for _, fn := range onRollback {
+		fn(err)
+	}
+}
+
+func (tx *Tx) hooks() ([]func(), []func(error)) {
+	tx.hookMu.Lock()
+	defer tx.hookMu.Unlock()
+	onCommit, onRollback := tx.onCommit, tx.onRollback
+	tx.onCommit, tx.onRollback = nil, nil
+	return onCommit, onRollback
+}
+
+// Run the function in a savepoint of the current transaction,
+// rollback to the savepoint when the function return an error, or release it when success.
+//
+// NOTE: this shadows the sql.Tx.Commit, the transaction is committed by the outside Commit caller,
+// call tx.Tx.Commit() if you really need to commit it by manully.
+func (tx *Tx) Commit(fn func(*Tx) error) error {
+	return tx.savepoint(tx.context(), fn)
+}
+
+// Same as Commit, the opts is ignored because the transaction has began.
+func (tx *Tx) CommitContext(ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	return tx.savepoint(ctx, fn)
+}
+
+func (tx *Tx) savepoint(ctx context.Context, fn func(*Tx) error) error {
+	name := fmt.Sprintf("qsql_sp_%d", atomic.AddInt32(&tx.savepointIdx, 1))
+	saveSql, rollbackSql, releaseSql := savepointSql(tx.drvName, name)
+	if _, err := tx.Tx.ExecContext(ctx, saveSql); err != nil {
+		return fmt.Errorf("%s: %w", saveSql, err)
+	}
+	rollback := func() {
+		if _, err := tx.Tx.ExecContext(ctx, rollbackSql); err != nil {
+			// roll back error is a serious error
+			log.Error(ctx, "rollback savepoint failed", "section", tx.db.Name(), "driver", tx.drvName, "sql", rollbackSql, "err", errors.As(err))
+		}
+	}
+	commitMark, rollbackMark := tx.hookMark()
+	// a panic is not recovered here to keep the stack, the outside Commit rollback the whole transaction.
+	if err := fn(tx); err != nil {
+		rollback()
+		for _, h := range tx.hookReset(commitMark, rollbackMark) {
+			h(err)
+		}
+		return err
+	}
+	if len(releaseSql) > 0 {
+		if _, err := tx.Tx.ExecContext(ctx, releaseSql); err != nil {
+			return fmt.Errorf("%s: %w", releaseSql, err)
+		}
+	}
+	return nil
+}
+
+func commitTx(txer Txer, ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	db, _ := txer.(*DB)
+	if tx := db.ctxTx(ctx); tx != nil {
+		// join the tx of context
+		return tx.savepoint(ctx, fn)
+	}
+	op := &Operation{Name: "Commit", Driver: txerDrvName(txer)}
+	return runOp(db, nil, ctx, nil, op, func(ctx context.Context, conn sqlConn, op *Operation) error {
+		return beginCommit(txer, ctx, opts, fn)
+	})
+}
+
+// Return the driver name of txer, it's detected from the sql.DB when the txer is not a BuilderDriver.
+func txerDrvName(txer Txer) string {
+	switch t := txer.(type) {
+	case BuilderDriver:
+		return t.DriverName()
+	case *sql.DB:
+		return sqlDBDrvName(t)
+	}
+	return ""
+}
+
+func beginCommit(txer Txer, ctx context.Context, opts *sql.TxOptions, fn func(*Tx) error) error {
+	sqlTx, err := txer.BeginTx(ctx, opts)
+	if err != nil {
+		// keep the error of driver for the classifiers
+		return err
+	}
+	tx := newTx(txerDrvName(txer), sqlTx)
+	tx.ctx = ctx
+	if db, ok := txer.(*DB); ok {
+		tx.db = db
+	}
+	if err := runTx(tx, ctx, fn); err != nil {
+		return err
+	}
+	// the hooks run out of the recover of fn, the transaction has been committed when they panic.
+	tx.afterCommit()
+	return nil
+}
+
+// Run the fn in tx and commit it, rollback when the fn failed or panic.
+// Same as Rollback, but logging with the ctx and db of Commit.
+func (tx *Tx) rollback(ctx context.Context) {
+	if err := tx.Tx.Rollback(); err != nil {
+		if err == sql.ErrTxDone {
+			return
+		}
+		log.Error(ctx, "rollback failed", "section", tx.db.Name(), "driver", tx.drvName, "err", errors.As(err))
+	}
+}
+
+func runTx(tx *Tx, ctx context.Context, fn func(*Tx) error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		// release the connection before panic again.
+		tx.rollback(ctx)
+		panicErr := ErrCommitPanic.As(fmt.Sprint(r), string(debug.Stack()))
+		tx.afterRollback(panicErr)
+		if tx.db == nil || !tx.db.options().panicAsError {
+			panic(r)
+		}
+		err = panicErr
+	}()
+
+	if err := fn(tx); err != nil {
+		tx.rollback(ctx)
+		tx.afterRollback(err)
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		// the context is done when the fn running, the sql.Tx has been rollback or will be rollback.
+		tx.rollback(ctx)
+		tx.afterRollback(err)
+		// keep the error of ctx for errors.Is
+		return err
+	}
+	if err := tx.Tx.Commit(); err != nil {
+		tx.afterRollback(err)
+		return err
+	}
+	return nil
+}
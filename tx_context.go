@@ -0,0 +1,51 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txCtxKey struct{}
+
+// Return a new context carried the tx,
+// the *Context methods of the qsql.DB which began the tx will run on the tx with this context,
+// and the CommitContext of the qsql.DB will run in a savepoint of the tx.
+func WithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txCtxKey{}, tx)
+}
+
+// Return the tx carried by the context, nil if not found.
+func TxFromContext(ctx context.Context) *Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(txCtxKey{}).(*Tx)
+	return tx
+}
+
+// the common interface of sql.DB and sql.Tx
+type sqlConn interface {
+	Execer
+	Queryer
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// Return the tx carried by the context when it began from the db.
+func (db *DB) ctxTx(ctx context.Context) *Tx {
+	if db == nil {
+		return nil
+	}
+	tx := TxFromContext(ctx)
+	if tx == nil || tx.db != db {
+		return nil
+	}
+	return tx
+}
+
+// Return the tx carried by the context, or the pool of db.
+func (db *DB) conn(ctx context.Context) sqlConn {
+	if tx := db.ctxTx(ctx); tx != nil {
+		return tx.Tx
+	}
+	return db.DB
+}
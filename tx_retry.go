@@ -0,0 +1,93 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// The retry policy of CommitRetry
+type RetryPolicy struct {
+	// The max times to run the tx, default is 3.
+	Attempts int
+	// The wait duration before the first retry, it will be doubled for the next retry, default is 10ms.
+	Backoff time.Duration
+	// The max wait duration of a retry, default is 1s.
+	MaxBackoff time.Duration
+	// Checking the error is retryable or not,
+	// default is the deadlock or serialization failure of the driver.
+	Retryable func(drvName string, err error) bool
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	Attempts:   3,
+	Backoff:    10 * time.Millisecond,
+	MaxBackoff: time.Second,
+	Retryable:  isRetryableError,
+}
+
+func (p *RetryPolicy) fill() RetryPolicy {
+	r := defaultRetryPolicy
+	if p == nil {
+		return r
+	}
+	if p.Attempts > 0 {
+		r.Attempts = p.Attempts
+	}
+	if p.Backoff > 0 {
+		r.Backoff = p.Backoff
+	}
+	if p.MaxBackoff > 0 {
+		r.MaxBackoff = p.MaxBackoff
+	}
+	if p.Retryable != nil {
+		r.Retryable = p.Retryable
+	}
+	return r
+}
+
+// Same as Commit, but rerun the whole function in a new tx when the tx failed by a deadlock or serialization failure.
+// the policy is using the default when it's nil.
+func CommitRetry(txer Txer, policy *RetryPolicy, fn func(tx *Tx) error) error {
+	return commitRetry(txer, context.TODO(), nil, policy, fn)
+}
+
+// Same as CommitRetry with the context and options of tx.
+func CommitRetryContext(txer Txer, ctx context.Context, opts *sql.TxOptions, policy *RetryPolicy, fn func(tx *Tx) error) error {
+	return commitRetry(txer, ctx, opts, policy, fn)
+}
+
+func commitRetry(txer Txer, ctx context.Context, opts *sql.TxOptions, policy *RetryPolicy, fn func(*Tx) error) error {
+	if db, ok := txer.(*DB); ok && db.ctxTx(ctx) != nil {
+		// the outside tx should be retried instead of the savepoint.
+		return commitTx(txer, ctx, opts, fn)
+	}
+	p := policy.fill()
+	drvName := txerDrvName(txer)
+
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := commitTx(txer, ctx, opts, fn)
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.Attempts || !p.Retryable(drvName, err) {
+			return err
+		}
+
+		// sleep with jitter to avoid the conflict again.
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			// keep the last error unwrapped, like the *QueryError.
+			return fmt.Errorf("%w, the last error: %w", ctx.Err(), err)
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
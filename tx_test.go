@@ -0,0 +1,434 @@
+package qsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gwaylib/errors"
+	"github.com/lib/pq"
+)
+
+type txTestUser struct {
+	ID       int64  `db:"id,auto_increment"`
+	UserName string `db:"username"`
+}
+
+func openTestDB(t *testing.T) *DB {
+	mdb, err := Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keep one connection for the memory database
+	mdb.SetMaxOpenConns(1)
+	if _, err := mdb.Exec(
+		`CREATE TABLE user (
+		  "id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+		  "username" VARCHAR(32) NOT NULL UNIQUE
+		);`); err != nil {
+		t.Fatal(err)
+	}
+	return mdb
+}
+
+func countTestUser(t *testing.T, mdb *DB) int {
+	count := 0
+	if err := mdb.QueryElem(&count, "SELECT COUNT(*) FROM user"); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestTxQuickSql(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	// the driver is remembered when begin from a raw sql.DB
+	if err := Commit(mdb.DB, func(tx *Tx) error {
+		if tx.DriverName() != DRV_NAME_SQLITE3 || tx.StmtIn(0, 2) != "?,?" {
+			t.Fatalf("expect the driver of sql.DB, but: %s", tx.DriverName())
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mdb.Commit(func(tx *Tx) error {
+		var q QuickSql = tx
+		if _, err := q.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+			return err
+		}
+		u := &txTestUser{}
+		if err := q.QueryStruct(u, "SELECT * FROM user WHERE username=?", "t1"); err != nil {
+			return err
+		}
+		if u.ID != 1 {
+			t.Fatalf("expect id 1, but: %+v", u)
+		}
+		names := []string{}
+		if err := q.QueryElems(&names, "SELECT username FROM user"); err != nil {
+			return err
+		}
+		_, data, err := q.QueryPageMap("SELECT username FROM user")
+		if err != nil {
+			return err
+		}
+		if len(names) != 1 || len(data) != 1 {
+			t.Fatalf("unexpect data: %+v, %+v", names, data)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if count := countTestUser(t, mdb); count != 1 {
+		t.Fatalf("expect committed, but count: %d", count)
+	}
+}
+
+// record the options to begin the tx
+type optsTestTxer struct {
+	*sql.DB
+	opts *sql.TxOptions
+}
+
+func (o *optsTestTxer) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	o.opts = opts
+	return o.DB.BeginTx(ctx, opts)
+}
+
+// fail to begin the tx with the error
+type errTestTxer struct {
+	*sql.DB
+	err error
+}
+
+func (e *errTestTxer) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return nil, e.err
+}
+
+func TestCommitError(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	for _, err := range []error{driver.ErrBadConn, &mysql.MySQLError{Number: 2006}} {
+		if err := Commit(&errTestTxer{DB: mdb.DB, err: err}, func(tx *Tx) error {
+			return nil
+		}); !IsConnectionError(err) {
+			t.Fatalf("expect connection error, but: %v", err)
+		}
+	}
+	if err := Commit(&errTestTxer{DB: mdb.DB, err: &pq.Error{Code: "40P01"}}, func(tx *Tx) error {
+		return nil
+	}); !IsDeadlock(err) {
+		t.Fatalf("expect deadlock, but: %v", err)
+	}
+
+	// the savepoint failed
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	if err := mdb.Commit(func(tx *Tx) error {
+		return tx.CommitContext(ctx, nil, func(tx *Tx) error {
+			return nil
+		})
+	}); !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, but: %v", err)
+	}
+}
+
+func TestCommitContext(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	txer := &optsTestTxer{DB: mdb.DB}
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}
+	if err := CommitContext(txer, context.TODO(), opts, func(tx *Tx) error {
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if txer.opts != opts {
+		t.Fatalf("expect the options passed, but: %+v", txer.opts)
+	}
+
+	// canceled when running
+	ctx, cancel := context.WithCancel(context.TODO())
+	err := mdb.CommitContext(ctx, nil, func(tx *Tx) error {
+		if _, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !stderrors.Is(err, context.Canceled) || !errors.Equal(err, context.Canceled) {
+		t.Fatalf("expect canceled, but: %v", err)
+	}
+	if count := countTestUser(t, mdb); count != 0 {
+		t.Fatalf("expect rollback, but count: %d", count)
+	}
+
+	// timeout when running
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer timeoutCancel()
+	err = mdb.CommitContext(timeoutCtx, nil, func(tx *Tx) error {
+		<-timeoutCtx.Done()
+		return nil
+	})
+	if !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect timeout, but: %v", err)
+	}
+
+	// canceled before begin
+	if err := mdb.CommitContext(ctx, nil, func(tx *Tx) error {
+		t.Fatal("expect not run")
+		return nil
+	}); err == nil {
+		t.Fatal("expect canceled")
+	}
+}
+
+func TestTxSavepoint(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	errInner := errors.New("inner failed")
+	if err := mdb.Commit(func(tx *Tx) error {
+		if _, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+			return errors.As(err)
+		}
+		// rollback to the savepoint
+		if err := tx.Commit(func(tx *Tx) error {
+			if _, err := tx.InsertStruct(&txTestUser{UserName: "t2"}, "user"); err != nil {
+				return errors.As(err)
+			}
+			return errInner
+		}); !errInner.Equal(err) {
+			t.Fatalf("expect inner error, but: %v", err)
+		}
+		// release the savepoint
+		return tx.Commit(func(tx *Tx) error {
+			_, err := tx.InsertStruct(&txTestUser{UserName: "t3"}, "user")
+			return err
+		})
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	if err := mdb.QueryElems(&names, "SELECT username FROM user ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "t1" || names[1] != "t3" {
+		t.Fatalf("expect [t1 t3], but: %+v", names)
+	}
+}
+
+func TestTxPanic(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	insertAndPanic := func(tx *Tx) error {
+		if _, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+			return errors.As(err)
+		}
+		panic("testing panic")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "testing panic" {
+				t.Fatalf("expect panic again, but: %v", r)
+			}
+		}()
+		mdb.Commit(insertAndPanic)
+	}()
+	// the only one connection would be blocked if the tx is not rollback.
+	if count := countTestUser(t, mdb); count != 0 {
+		t.Fatalf("expect rollback, but count: %d", count)
+	}
+
+	mdb.SetCommitPanicAsError(true)
+	if err := Commit(mdb, insertAndPanic); !ErrCommitPanic.Equal(err) {
+		t.Fatalf("expect ErrCommitPanic, but: %v", err)
+	}
+	if count := countTestUser(t, mdb); count != 0 {
+		t.Fatalf("expect rollback, but count: %d", count)
+	}
+
+	// panic in savepoint
+	err := mdb.Commit(func(tx *Tx) error {
+		return tx.Commit(insertAndPanic)
+	})
+	if !ErrCommitPanic.Equal(err) {
+		t.Fatalf("expect ErrCommitPanic, but: %v", err)
+	}
+	// the stack is where the panic happened
+	if !strings.Contains(err.Error(), "TestTxPanic.func1") {
+		t.Fatalf("expect the stack of panic, but: %v", err)
+	}
+	if count := countTestUser(t, mdb); count != 0 {
+		t.Fatalf("expect rollback, but count: %d", count)
+	}
+
+	// panic in the hook after committed
+	rollbacks := 0
+	func() {
+		defer func() {
+			if r := recover(); r != "hook panic" {
+				t.Fatalf("expect the hook panic, but: %v", r)
+			}
+		}()
+		mdb.Commit(func(tx *Tx) error {
+			tx.OnCommit(func() { panic("hook panic") })
+			tx.OnRollback(func(error) { rollbacks++ })
+			_, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user")
+			return err
+		})
+	}()
+	if count := countTestUser(t, mdb); count != 1 || rollbacks != 0 {
+		t.Fatalf("expect committed, but count: %d, rollbacks: %d", count, rollbacks)
+	}
+}
+
+func TestRetryableError(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+	if !isRetryableError(DRV_NAME_MYSQL, deadlock) {
+		t.Fatal("expect mysql deadlock retryable")
+	}
+	// the wrapped error lost the driver type.
+	if !isRetryableError(DRV_NAME_MYSQL, errors.As(deadlock)) {
+		t.Fatal("expect wrapped mysql deadlock retryable")
+	}
+	if isRetryableError(DRV_NAME_MYSQL, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}) {
+		t.Fatal("expect mysql duplicate not retryable")
+	}
+	if !isRetryableError(DRV_NAME_POSTGRES, &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"}) {
+		t.Fatal("expect postgres serialization failure retryable")
+	}
+	if !isRetryableError(DRV_NAME_POSTGRES, fmt.Errorf("commit: %w", &pq.Error{Code: "40P01"})) {
+		t.Fatal("expect postgres deadlock retryable")
+	}
+}
+
+func TestCommitRetry(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	times := 0
+	if err := mdb.CommitRetry(&RetryPolicy{Attempts: 3, Backoff: time.Millisecond}, func(tx *Tx) error {
+		times++
+		if _, err := tx.InsertStruct(&txTestUser{UserName: "t1"}, "user"); err != nil {
+			return errors.As(err)
+		}
+		if times < 3 {
+			return errors.New("database is locked")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if times != 3 {
+		t.Fatalf("expect run 3 times, but: %d", times)
+	}
+	if count := countTestUser(t, mdb); count != 1 {
+		t.Fatalf("expect count 1, but: %d", count)
+	}
+
+	times = 0
+	errNotRetry := errors.New("not retryable")
+	if err := mdb.CommitRetry(nil, func(tx *Tx) error {
+		times++
+		return errNotRetry
+	}); !errNotRetry.Equal(err) || times != 1 {
+		t.Fatalf("expect run 1 times, but: %d, %v", times, err)
+	}
+}
+
+func TestTxHook(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	events := []string{}
+	errInner := errors.New("inner failed")
+	if err := mdb.Commit(func(tx *Tx) error {
+		tx.OnCommit(func() { events = append(events, "commit") })
+		tx.OnRollback(func(err error) { events = append(events, "rollback") })
+		tx.Commit(func(tx *Tx) error {
+			tx.OnCommit(func() { events = append(events, "sp commit") })
+			tx.OnRollback(func(err error) {
+				if !errInner.Equal(err) {
+					t.Fatalf("expect inner error, but: %v", err)
+				}
+				events = append(events, "sp rollback")
+			})
+			return errInner
+		})
+		if len(events) != 1 || events[0] != "sp rollback" {
+			t.Fatalf("expect savepoint rollback, but: %+v", events)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1] != "commit" {
+		t.Fatalf("expect commit, but: %+v", events)
+	}
+
+	events = []string{}
+	if err := mdb.Commit(func(tx *Tx) error {
+		tx.OnCommit(func() { events = append(events, "commit") })
+		tx.OnRollback(func(err error) { events = append(events, "rollback") })
+		return errInner
+	}); !errInner.Equal(err) {
+		t.Fatalf("expect inner error, but: %v", err)
+	}
+	if len(events) != 1 || events[0] != "rollback" {
+		t.Fatalf("expect rollback, but: %+v", events)
+	}
+}
+
+func TestTxContext(t *testing.T) {
+	mdb := openTestDB(t)
+	defer Close(mdb)
+
+	// the repository code is transaction-agnostic.
+	addUser := func(ctx context.Context, name string) error {
+		_, err := mdb.InsertStructContext(ctx, &txTestUser{UserName: name}, "user")
+		return err
+	}
+
+	// the only one connection would be blocked if the ctx tx is not used.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	errRollback := errors.New("rollback")
+	if err := mdb.CommitContext(ctx, nil, func(tx *Tx) error {
+		txCtx := WithTx(ctx, tx)
+		if err := addUser(txCtx, "t1"); err != nil {
+			return errors.As(err)
+		}
+		// join the tx by savepoint
+		if err := mdb.CommitContext(txCtx, nil, func(tx *Tx) error {
+			return addUser(txCtx, "t2")
+		}); err != nil {
+			return errors.As(err)
+		}
+		count := 0
+		if err := mdb.QueryElemContext(txCtx, &count, "SELECT COUNT(*) FROM user"); err != nil {
+			return errors.As(err)
+		}
+		if count != 2 {
+			t.Fatalf("expect count 2 in tx, but: %d", count)
+		}
+		return errRollback
+	}); !errRollback.Equal(err) {
+		t.Fatal(err)
+	}
+	if count := countTestUser(t, mdb); count != 0 {
+		t.Fatalf("expect rollback, but count: %d", count)
+	}
+}